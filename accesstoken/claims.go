@@ -0,0 +1,22 @@
+package accesstoken
+
+import "github.com/Grubiha/auth_session/domain"
+
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	SessionId string `json:"sid"`
+	UserName  string `json:"name"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (c Claims) SessionInfo() domain.SessionInfo {
+	return domain.SessionInfo{
+		SessionId: c.SessionId,
+		UserId:    c.Subject,
+		UserName:  c.UserName,
+		UserRole:  c.Role,
+	}
+}
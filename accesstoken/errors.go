@@ -0,0 +1,24 @@
+package accesstoken
+
+import (
+	"errors"
+
+	"github.com/Grubiha/auth_session/domain"
+)
+
+// Ошибки проверки оборачивают domain.ErrInvalidToken, чтобы middleware
+// отвечали на них 401 так же, как на неизвестную сессию
+var (
+	ErrMalformedToken   = errors.Join(domain.ErrInvalidToken, errors.New("malformed access token"))
+	ErrInvalidSignature = errors.Join(domain.ErrInvalidToken, errors.New("invalid access token signature"))
+	ErrUnknownKey       = errors.Join(domain.ErrInvalidToken, errors.New("unknown access token key"))
+	ErrTokenExpired     = errors.Join(domain.ErrInvalidToken, errors.New("access token expired"))
+	ErrInvalidClaims    = errors.Join(domain.ErrInvalidToken, errors.New("invalid access token claims"))
+
+	// Недоступный JWKS не повод разлогинивать клиента, он повторит запрос
+	ErrJwksFetchFailed = errors.Join(domain.ErrUnavailable, errors.New("jwks fetch failed"))
+
+	ErrNoSigningKeys  = errors.New("no access token signing keys")
+	ErrUnsupportedKey = errors.New("unsupported access token key")
+	ErrUnsupportedJwk = errors.New("unsupported jwk")
+)
@@ -0,0 +1,91 @@
+package accesstoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Выпускает access-токены. Подписывает первым ключом, остальные ключи
+// только публикуются в JWKS, чтобы токены до ротации оставались действительными
+type Issuer struct {
+	keys   []Key
+	issuer string
+	ttl    time.Duration
+}
+
+func NewIssuer(keys []Key, issuer string, ttl time.Duration) (*Issuer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+	return &Issuer{
+		keys:   keys,
+		issuer: issuer,
+		ttl:    ttl,
+	}, nil
+}
+
+func NewIssuerFromConfig(cfg config.AccessToken) (*Issuer, error) {
+	keys := make([]Key, 0, len(cfg.ACCESS_TOKEN_KEY_FILES))
+	for _, path := range cfg.ACCESS_TOKEN_KEY_FILES {
+		key, err := LoadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewIssuer(keys, cfg.ACCESS_TOKEN_ISSUER, cfg.ACCESS_TOKEN_TTL)
+}
+
+func (i *Issuer) Issue(info domain.SessionInfo) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+
+	key := i.keys[0]
+	headerJson, err := json.Marshal(header{
+		Alg: key.Alg,
+		Typ: "JWT",
+		Kid: key.Kid,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claimsJson, err := json.Marshal(Claims{
+		Issuer:    i.issuer,
+		Subject:   info.UserId,
+		SessionId: info.SessionId,
+		UserName:  info.UserName,
+		Role:      info.UserRole,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), time.Unix(expiresAt.Unix(), 0), nil
+}
+
+func (i *Issuer) Jwks() Jwks {
+	jwks := Jwks{Keys: make([]Jwk, 0, len(i.keys))}
+	for _, key := range i.keys {
+		jwks.Keys = append(jwks.Keys, key.PublicJwk())
+	}
+	return jwks
+}
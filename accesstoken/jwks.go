@@ -0,0 +1,77 @@
+package accesstoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+type verificationKey struct {
+	alg       string
+	publicKey crypto.PublicKey
+}
+
+func (jwks Jwks) verificationKeys() (map[string]verificationKey, error) {
+	keys := make(map[string]verificationKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.Kid] = verificationKey{
+			alg:       jwk.Alg,
+			publicKey: publicKey,
+		}
+	}
+	return keys, nil
+}
+
+func (jwk Jwk) publicKey() (crypto.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, errors.Join(ErrUnsupportedJwk, err)
+	}
+
+	switch {
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == AlgEdDSA:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.Join(ErrUnsupportedJwk, errors.New("invalid Ed25519 key size"))
+		}
+		return ed25519.PublicKey(x), nil
+	case jwk.Kty == "EC" && jwk.Crv == "P-256" && jwk.Alg == AlgES256:
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, errors.Join(ErrUnsupportedJwk, err)
+		}
+		publicKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, errors.Join(ErrUnsupportedJwk, errors.New("point is not on P-256"))
+		}
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("%w: kty=%q crv=%q alg=%q", ErrUnsupportedJwk, jwk.Kty, jwk.Crv, jwk.Alg)
+	}
+}
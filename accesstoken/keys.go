@@ -0,0 +1,193 @@
+package accesstoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+)
+
+// Ключ подписи; Kid вычисляется как JWK thumbprint (RFC 7638) открытого ключа
+type Key struct {
+	Kid        string
+	Alg        string
+	privateKey crypto.Signer
+}
+
+func NewKey(privateKey crypto.Signer) (Key, error) {
+	jwk, err := publicJwk(privateKey.Public())
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{
+		Kid:        jwk.Kid,
+		Alg:        jwk.Alg,
+		privateKey: privateKey,
+	}, nil
+}
+
+func GenerateKey(alg string) (Key, error) {
+	switch alg {
+	case AlgEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		return NewKey(privateKey)
+	case AlgES256:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		return NewKey(privateKey)
+	default:
+		return Key{}, fmt.Errorf("%w: %q", ErrUnsupportedKey, alg)
+	}
+}
+
+func LoadKeyFile(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	return ParseKeyPEM(data)
+}
+
+func ParseKeyPEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.Join(ErrUnsupportedKey, errors.New("no pem block"))
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("%w: pem block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return Key{}, errors.Join(ErrUnsupportedKey, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, privateKey)
+	}
+	return NewKey(signer)
+}
+
+func (k Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k Key) PublicJwk() Jwk {
+	jwk, _ := publicJwk(k.privateKey.Public())
+	return jwk
+}
+
+func (k Key) sign(signingInput []byte) ([]byte, error) {
+	switch privateKey := k.privateKey.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(privateKey, signingInput), nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signingInput)
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS хранит подпись ES256 как r || s фиксированной длины
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, k.privateKey)
+	}
+}
+
+func verifySignature(publicKey crypto.PublicKey, alg string, signingInput, signature []byte) bool {
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA && ed25519.Verify(publicKey, signingInput, signature)
+	case *ecdsa.PublicKey:
+		if alg != AlgES256 || len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(publicKey, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func publicJwk(publicKey crypto.PublicKey) (Jwk, error) {
+	var jwk Jwk
+	var thumbprintInput []byte
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk = Jwk{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			Alg: AlgEdDSA,
+		}
+		thumbprintInput, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return Jwk{}, errors.Join(ErrUnsupportedKey, errors.New("only P-256 curve is supported"))
+		}
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		publicKey.X.FillBytes(x)
+		publicKey.Y.FillBytes(y)
+		jwk = Jwk{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(x),
+			Y:   base64.RawURLEncoding.EncodeToString(y),
+			Alg: AlgES256,
+		}
+		thumbprintInput, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y})
+	default:
+		return Jwk{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, publicKey)
+	}
+
+	thumbprint := sha256.Sum256(thumbprintInput)
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	jwk.Use = "sig"
+	return jwk, nil
+}
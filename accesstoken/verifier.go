@@ -0,0 +1,221 @@
+package accesstoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Grubiha/auth_session/domain"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Допустимое расхождение часов между сервисами
+	verifyLeeway = 30 * time.Second
+	// Не чаще одного запроса JWKS за интервал при неизвестном kid
+	jwksRefreshInterval = 30 * time.Second
+	// Пауза после неудачной загрузки, чтобы недоступный JWKS не получал
+	// запрос на каждый токен
+	jwksFailureBackoff = time.Second
+	jwksFetchTimeout   = 10 * time.Second
+	// Ограничение размера ответа JWKS
+	jwksMaxResponseBytes = 1 << 20
+)
+
+// Проверяет access-токены без обращения к Redis и PostgreSQL.
+// Отозванная сессия остается действительной для проверяющего до истечения
+// access-токена, поэтому срок его жизни должен быть коротким
+type Verifier struct {
+	issuer string
+
+	mu          sync.RWMutex
+	keys        map[string]verificationKey
+	jwksUrl     string
+	httpClient  *http.Client
+	lastFetchAt time.Time
+	failedAt    time.Time
+	fetchErr    error
+	refresh     singleflight.Group
+}
+
+// Проверяет токены ключами из jwks, например из Issuer.Jwks
+func NewVerifier(jwks Jwks, issuer string) (*Verifier, error) {
+	keys, err := jwks.verificationKeys()
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		issuer: issuer,
+		keys:   keys,
+	}, nil
+}
+
+// Загружает ключи с jwksUrl при первой проверке и перечитывает их,
+// когда встречает неизвестный kid после ротации
+func NewRemoteVerifier(jwksUrl string, issuer string, httpClient *http.Client) *Verifier {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: jwksFetchTimeout}
+	}
+	return &Verifier{
+		issuer:     issuer,
+		keys:       map[string]verificationKey{},
+		jwksUrl:    jwksUrl,
+		httpClient: httpClient,
+	}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, errors.Join(ErrMalformedToken, err)
+	}
+
+	key, err := v.key(ctx, h.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	// Алгоритм берем из ключа, а не из заголовка токена
+	if h.Alg != key.alg || !verifySignature(key.publicKey, key.alg, []byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	if claims.Issuer != v.issuer || claims.Subject == "" || claims.SessionId == "" {
+		return Claims{}, ErrInvalidClaims
+	}
+	if !time.Unix(claims.ExpiresAt, 0).Add(verifyLeeway).After(time.Now()) {
+		return Claims{}, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// Позволяет использовать Verifier в middleware.NewAuthMiddleware
+// и grpcapi.NewAuthInterceptor вместо репозитория сессий
+func (v *Verifier) FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error) {
+	claims, err := v.Verify(ctx, dto.Token)
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+	return claims.SessionInfo(), nil
+}
+
+func (v *Verifier) key(ctx context.Context, kid string) (verificationKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if v.jwksUrl == "" {
+		return verificationKey{}, ErrUnknownKey
+	}
+
+	// Параллельные запросы с неизвестным kid ждут одну загрузку,
+	// а блокировка не удерживается на время HTTP-запроса
+	_, err, _ := v.refresh.Do("jwks", func() (interface{}, error) {
+		v.mu.Lock()
+		// Ключ мог загрузить предыдущий запрос
+		if _, ok := v.keys[kid]; ok {
+			v.mu.Unlock()
+			return nil, nil
+		}
+		// Токены с выдуманным kid не должны превращаться в запросы к JWKS
+		if time.Since(v.lastFetchAt) < jwksRefreshInterval {
+			v.mu.Unlock()
+			return nil, nil
+		}
+		// Пока JWKS недоступен, отвечаем последней ошибкой загрузки,
+		// а не ErrUnknownKey: токен может быть действительным
+		if v.fetchErr != nil && time.Since(v.failedAt) < jwksFailureBackoff {
+			err := v.fetchErr
+			v.mu.Unlock()
+			return nil, err
+		}
+		v.mu.Unlock()
+
+		// Результат нужен всем ожидающим, отмена первого запроса не должна его прерывать
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+		defer cancel()
+		keys, err := v.fetchKeys(ctx)
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if err != nil {
+			v.failedAt = time.Now()
+			v.fetchErr = err
+			return nil, err
+		}
+		v.keys = keys
+		v.lastFetchAt = time.Now()
+		v.fetchErr = nil
+		return nil, nil
+	})
+	if err != nil {
+		return verificationKey{}, err
+	}
+
+	v.mu.RLock()
+	key, ok = v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	return verificationKey{}, ErrUnknownKey
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]verificationKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksUrl, nil)
+	if err != nil {
+		return nil, errors.Join(ErrJwksFetchFailed, err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Join(ErrJwksFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrJwksFetchFailed, resp.StatusCode)
+	}
+
+	var jwks Jwks
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxResponseBytes)).Decode(&jwks); err != nil {
+		return nil, errors.Join(ErrJwksFetchFailed, err)
+	}
+	keys, err := jwks.verificationKeys()
+	if err != nil {
+		return nil, errors.Join(ErrJwksFetchFailed, err)
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.Join(ErrMalformedToken, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.Join(ErrMalformedToken, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package accesstoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/domain"
+)
+
+func newTestIssuer(t *testing.T) *Issuer {
+	t.Helper()
+	key, err := GenerateKey(AlgEdDSA)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	issuer, err := NewIssuer([]Key{key}, "auth_session", time.Minute)
+	if err != nil {
+		t.Fatalf("NewIssuer: %v", err)
+	}
+	return issuer
+}
+
+func issueToken(t *testing.T, issuer *Issuer) string {
+	t.Helper()
+	token, _, err := issuer.Issue(domain.SessionInfo{SessionId: "session", UserId: "user", UserRole: domain.UserRoleUser})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+func TestRemoteVerifierFetchesOnce(t *testing.T) {
+	issuer := newTestIssuer(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+
+	verifier := NewRemoteVerifier(server.URL, "auth_session", nil)
+	token := issueToken(t, issuer)
+
+	// Параллельные проверки с еще не загруженным ключом ждут одну загрузку
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := verifier.Verify(context.Background(), token); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestRemoteVerifierRateLimitsUnknownKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+
+	verifier := NewRemoteVerifier(server.URL, "auth_session", nil)
+	if _, err := verifier.Verify(context.Background(), issueToken(t, issuer)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Токены чужого издателя не должны вызывать повторных загрузок
+	foreign := issueToken(t, newTestIssuer(t))
+	for i := 0; i < 5; i++ {
+		_, err := verifier.Verify(context.Background(), foreign)
+		if !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("Verify foreign token: error = %v, want %v", err, ErrUnknownKey)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestRemoteVerifierKnownKeyNotBlockedByFetch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Первая загрузка отвечает сразу, следующая зависает
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+	defer close(release)
+
+	verifier := NewRemoteVerifier(server.URL, "auth_session", nil)
+	token := issueToken(t, issuer)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Разрешаем повторную загрузку и запускаем ее неизвестным kid
+	verifier.mu.Lock()
+	verifier.lastFetchAt = time.Time{}
+	verifier.mu.Unlock()
+	go verifier.Verify(context.Background(), issueToken(t, newTestIssuer(t)))
+	for fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(context.Background(), token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Verify with a known key waited for the JWKS fetch")
+	}
+}
+
+func TestRemoteVerifierFetchFailureIsUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t)
+	var healthy atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+
+	verifier := NewRemoteVerifier(server.URL, "auth_session", nil)
+	token := issueToken(t, issuer)
+
+	// Во время паузы после сбоя JWKS не запрашивается, но ошибка остается
+	// ошибкой загрузки, а не неизвестным ключом
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), token)
+		if !errors.Is(err, ErrJwksFetchFailed) || !errors.Is(err, domain.ErrUnavailable) {
+			t.Fatalf("Verify #%d: error = %v, want %v", i, err, ErrJwksFetchFailed)
+		}
+		if errors.Is(err, domain.ErrInvalidToken) {
+			t.Fatalf("Verify #%d: fetch failure must not look like an invalid token", i)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+
+	// После паузы ключи загружаются заново
+	healthy.Store(true)
+	verifier.mu.Lock()
+	verifier.failedAt = time.Time{}
+	verifier.mu.Unlock()
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify after recovery: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/Grubiha/auth_session/accesstoken"
+)
+
+// Генерирует ключ подписи access-токенов в PEM. При ротации новый файл
+// ставится первым в ACCESS_TOKEN_KEY_FILES, старый остается следом до истечения ACCESS_TOKEN_TTL
+func main() {
+	alg := flag.String("alg", accesstoken.AlgEdDSA, "signing algorithm: EdDSA or ES256")
+	out := flag.String("out", "", "write key to file instead of stdout")
+	flag.Parse()
+
+	key, err := accesstoken.GenerateKey(*alg)
+	if err != nil {
+		slog.Error("failed to generate key", "error", err)
+		os.Exit(1)
+	}
+	data, err := key.MarshalPEM()
+	if err != nil {
+		slog.Error("failed to encode key", "error", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+	} else if err := os.WriteFile(*out, data, 0o600); err != nil {
+		slog.Error("failed to write key", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("key generated", "kid", key.Kid, "alg", key.Alg)
+}
@@ -1,36 +1,229 @@
-package migrator
+package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/migrations"
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
+const (
+	exitOk    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var errUsage = errors.New("usage error")
+
+var commands = map[string]bool{
+	"up":      true,
+	"down":    true,
+	"goto":    true,
+	"force":   true,
+	"version": true,
+	"status":  true,
+}
+
+const usage = `Usage: migrator [flags] <command> [args]
+
+Commands:
+  up [N]          apply all pending migrations or the next N
+  down N          roll back N migrations
+  goto VERSION    migrate up or down to VERSION
+  force VERSION   set VERSION without running migrations and clear the dirty flag
+  version         print the current version
+  status          list migrations and mark applied ones
+
+Flags:
+`
+
 func main() {
-	cfg, err := config.Load()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "load environment from file, empty to use only the process environment")
+	dryRun := flags.Bool("dry-run", false, "print migrations that up, down or goto would run without applying them")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOk
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 || !commands[flags.Arg(0)] {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*envFile)
 	if err != nil {
 		slog.Error("failed to load config", "error", err)
-		os.Exit(1)
+		return exitError
 	}
-	slog.Debug("config loaded", "config", cfg)
 
-	databaseUrl := config.PgUrl(cfg)
-	slog.Debug("connecting to database", "url", databaseUrl)
+	sourceDriver, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		slog.Error("failed to read migrations", "error", err)
+		return exitError
+	}
 
-	m, err := migrate.New("file://migrations", databaseUrl)
+	// Драйвер pgx поддерживает все значения sslmode, в отличие от lib/pq
+	databaseUrl := "pgx5" + strings.TrimPrefix(config.PgUrl(cfg), "postgres")
+	m, err := migrate.NewWithSourceInstance("iofs", sourceDriver, databaseUrl)
 	if err != nil {
-		slog.Error("Failed to create migrate instance", "error", err.Error())
-		os.Exit(1)
+		slog.Error("failed to create migrate instance", "error", err)
+		return exitError
+	}
+	defer m.Close()
+
+	err = runCommand(m, sourceDriver, flags.Arg(0), flags.Args()[1:], *dryRun)
+	switch {
+	case errors.Is(err, errUsage):
+		slog.Error(err.Error())
+		flags.Usage()
+		return exitUsage
+	case errors.Is(err, migrate.ErrNoChange):
+		slog.Info("no change")
+		return exitOk
+	case err != nil:
+		slog.Error("migration failed", "command", flags.Arg(0), "error", err)
+		return exitError
+	}
+	return exitOk
+}
+
+func loadConfig(envFile string) (config.Config, error) {
+	if envFile == "" {
+		return config.Get()
 	}
+	return config.Load(envFile)
+}
+
+func runCommand(m *migrate.Migrate, sourceDriver source.Driver, command string, args []string, dryRun bool) error {
+	switch command {
+	case "up":
+		if len(args) > 1 {
+			return fmt.Errorf("%w: up takes at most one argument", errUsage)
+		}
+		steps := -1
+		if len(args) == 1 {
+			n, err := parsePositive(args[0])
+			if err != nil {
+				return err
+			}
+			steps = n
+		}
+		if dryRun {
+			return printUpPlan(m, sourceDriver, steps, -1)
+		}
+		if steps < 0 {
+			return logResult(m, m.Up())
+		}
+		return logResult(m, m.Steps(steps))
+
+	case "down":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: down requires the number of migrations", errUsage)
+		}
+		steps, err := parsePositive(args[0])
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return printDownPlan(m, sourceDriver, steps, -1)
+		}
+		return logResult(m, m.Steps(-steps))
+
+	case "goto":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return printGotoPlan(m, sourceDriver, uint(version))
+		}
+		return logResult(m, m.Migrate(uint(version)))
+
+	case "force":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Printf("would force version %d\n", version)
+			return nil
+		}
+		return logResult(m, m.Force(version))
+
+	case "version":
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d%s\n", version, dirtySuffix(dirty))
+		return nil
 
-	err = m.Up()
+	case "status":
+		return printStatus(m, sourceDriver)
+
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+func logResult(m *migrate.Migrate, err error) error {
 	if err != nil {
-		slog.Error("Failed to migrate database", "error", err.Error())
-	} else {
-		slog.Info("migrated database")
+		return err
+	}
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	slog.Info("migrated database", "version", version, "dirty", dirty)
+	return nil
+}
+
+func parsePositive(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: expected positive number, got %q", errUsage, arg)
+	}
+	return n, nil
+}
+
+func parseVersion(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected VERSION", errUsage)
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("%w: expected non-negative version, got %q", errUsage, args[0])
+	}
+	return version, nil
+}
+
+func dirtySuffix(dirty bool) string {
+	if dirty {
+		return " (dirty)"
 	}
+	return ""
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+type migrationFile struct {
+	version    uint
+	identifier string
+}
+
+// Все миграции источника по возрастанию версии
+func listMigrations(sourceDriver source.Driver) ([]migrationFile, error) {
+	var list []migrationFile
+	version, err := sourceDriver.First()
+	for err == nil {
+		identifier, readErr := migrationIdentifier(sourceDriver, version)
+		if readErr != nil {
+			return nil, readErr
+		}
+		list = append(list, migrationFile{version: version, identifier: identifier})
+		version, err = sourceDriver.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
+
+func migrationIdentifier(sourceDriver source.Driver, version uint) (string, error) {
+	r, identifier, err := sourceDriver.ReadUp(version)
+	if err != nil {
+		return "", err
+	}
+	r.Close()
+	return identifier, nil
+}
+
+// Текущая версия или -1, если миграции еще не применялись
+func currentVersion(m *migrate.Migrate) (int, bool, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return -1, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return int(version), dirty, nil
+}
+
+func printStatus(m *migrate.Migrate, sourceDriver source.Driver) error {
+	current, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	list, err := listMigrations(sourceDriver)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range list {
+		state := "pending"
+		switch {
+		case int(migration.version) == current && dirty:
+			state = "dirty"
+		case int(migration.version) <= current:
+			state = "applied"
+		}
+		fmt.Printf("%-8s %06d %s\n", state, migration.version, migration.identifier)
+	}
+	return nil
+}
+
+// steps < 0 означает все ожидающие миграции, target < 0 - без ограничения по версии
+func printUpPlan(m *migrate.Migrate, sourceDriver source.Driver, steps int, target int) error {
+	current, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	list, err := listMigrations(sourceDriver)
+	if err != nil {
+		return err
+	}
+
+	var plan []migrationFile
+	for _, migration := range list {
+		if int(migration.version) <= current {
+			continue
+		}
+		if target >= 0 && int(migration.version) > target {
+			break
+		}
+		if steps >= 0 && len(plan) == steps {
+			break
+		}
+		plan = append(plan, migration)
+	}
+	return printPlan("up", plan)
+}
+
+// steps < 0 означает без ограничения по количеству, target < 0 - откат всех миграций
+func printDownPlan(m *migrate.Migrate, sourceDriver source.Driver, steps int, target int) error {
+	current, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	list, err := listMigrations(sourceDriver)
+	if err != nil {
+		return err
+	}
+
+	var plan []migrationFile
+	for i := len(list) - 1; i >= 0; i-- {
+		migration := list[i]
+		if int(migration.version) > current {
+			continue
+		}
+		if int(migration.version) <= target {
+			break
+		}
+		if steps >= 0 && len(plan) == steps {
+			break
+		}
+		plan = append(plan, migration)
+	}
+	return printPlan("down", plan)
+}
+
+func printGotoPlan(m *migrate.Migrate, sourceDriver source.Driver, version uint) error {
+	if _, err := migrationIdentifier(sourceDriver, version); err != nil {
+		return fmt.Errorf("unknown version %d: %w", version, err)
+	}
+	current, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	if int(version) >= current {
+		return printUpPlan(m, sourceDriver, -1, int(version))
+	}
+	return printDownPlan(m, sourceDriver, -1, int(version))
+}
+
+func printPlan(direction string, plan []migrationFile) error {
+	if len(plan) == 0 {
+		return migrate.ErrNoChange
+	}
+	for _, migration := range plan {
+		fmt.Printf("would run %-4s %06d %s\n", direction, migration.version, migration.identifier)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Сверяет сессии в Redis и PostgreSQL. Единственная точка входа сверки:
+// cmd/server ее не запускает, для периодической работы нужен -periodic
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/db"
+	"github.com/Grubiha/auth_session/repos"
+)
+
+func main() {
+	periodic := flag.Bool("periodic", false, "run reconciliation every SESSION_RECONCILE_INTERVAL until stopped")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pgPool, err := db.NewPgPool(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to connect to postgres", "error", err)
+		os.Exit(1)
+	}
+	defer pgPool.Close()
+
+	redisClient, err := db.NewRedisClient(cfg.Redis)
+	if err != nil {
+		slog.Error("failed to create redis client", "error", err)
+		os.Exit(1)
+	}
+	defer redisClient.Close()
+
+	sessionRepo, err := repos.NewSessionRepository(pgPool, redisClient, cfg.Session)
+	if err != nil {
+		slog.Error("failed to create session repository", "error", err)
+		os.Exit(1)
+	}
+
+	if *periodic {
+		slog.Info("reconciler started", "interval", cfg.SESSION_RECONCILE_INTERVAL)
+		sessionRepo.RunReconciler(ctx)
+		return
+	}
+
+	report, err := sessionRepo.Reconcile(ctx)
+	if err != nil {
+		slog.Error("failed to reconcile sessions", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("sessions reconciled", "report", report)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/Grubiha/auth_session/accesstoken"
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/db"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/grpcapi"
+	"github.com/Grubiha/auth_session/handlers"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/Grubiha/auth_session/services"
+	"github.com/Grubiha/auth_session/usecases"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("server stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pgPool, err := db.NewPgPool(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer pgPool.Close()
+
+	redisClient, err := db.NewRedisClient(cfg.Redis)
+	if err != nil {
+		return fmt.Errorf("failed to create redis client: %w", err)
+	}
+	defer redisClient.Close()
+
+	// Иерархия ролей из таблицы roles заменяет встроенную до обработки запросов
+	roles, err := repos.NewRoleRepository(pgPool).List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+	if err := domain.SetRoles(roles); err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+
+	messages, err := services.NewMessageService(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create message service: %w", err)
+	}
+
+	// Подписанные access-токены включаются ACCESS_TOKEN_ENABLED
+	var accessTokens *accesstoken.Issuer
+	if cfg.ACCESS_TOKEN_ENABLED {
+		accessTokens, err = accesstoken.NewIssuerFromConfig(cfg.AccessToken)
+		if err != nil {
+			return fmt.Errorf("failed to load access token keys: %w", err)
+		}
+	}
+
+	userRepo := repos.NewUserRepository(pgPool, redisClient)
+	sessionRepo, err := repos.NewSessionRepository(pgPool, redisClient, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to create session repository: %w", err)
+	}
+	if err := sessionRepo.ScheduleCleanup(ctx); err != nil {
+		return fmt.Errorf("failed to schedule session cleanup: %w", err)
+	}
+	otpRepo, err := repos.NewOtpRepository(redisClient, cfg.Otp)
+	if err != nil {
+		return fmt.Errorf("failed to create otp repository: %w", err)
+	}
+
+	authUsecase, err := usecases.NewAuthUsecase(userRepo, sessionRepo, otpRepo, messages, accessTokens, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create auth usecase: %w", err)
+	}
+	sessionUsecase := usecases.NewSessionManageUsecase(sessionRepo)
+	userUsecase := usecases.NewUserManageUsecase(userRepo)
+
+	handler := handlers.NewHandler(authUsecase, sessionUsecase, userUsecase, cfg.SERVER_SESSION_COOKIE)
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.SERVER_PORT),
+		Handler:      handler.Routes(),
+		ReadTimeout:  cfg.SERVER_READ_TIMEOUT,
+		WriteTimeout: cfg.SERVER_WRITE_TIMEOUT,
+		IdleTimeout:  cfg.SERVER_IDLE_TIMEOUT,
+	}
+
+	// Фоновые задачи останавливаются вместе с сервером до закрытия соединений.
+	// Сверка Redis и PostgreSQL сюда не входит, ее запускает cmd/reconciler
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	for _, task := range []func(context.Context){
+		sessionRepo.RunActivityFlusher,
+		sessionRepo.RunJanitor,
+	} {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			task(backgroundCtx)
+		}()
+	}
+	defer func() {
+		stopBackground()
+		background.Wait()
+	}()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("server started", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// gRPC включается отдельным портом SERVER_GRPC_PORT
+	var grpcServer *grpc.Server
+	if cfg.SERVER_GRPC_PORT > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.SERVER_GRPC_PORT))
+		if err != nil {
+			return fmt.Errorf("failed to listen grpc port: %w", err)
+		}
+		grpcServer = grpcapi.NewServer(authUsecase, sessionUsecase, userUsecase).GRPCServer()
+		go func() {
+			slog.Info("grpc server started", "addr", listener.Addr().String())
+			serverErr <- grpcServer.Serve(listener)
+		}()
+		defer grpcServer.Stop()
+	}
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.SERVER_SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+
+	return nil
+}
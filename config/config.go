@@ -1,7 +1,10 @@
 package config
 
 import (
-	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,14 +14,23 @@ import (
 type Config struct {
 	Server
 	Postgres
+	Redis
 	Session
+	AccessToken
+	Otp
+	Exolve
+	Messages
 }
 
 type Server struct {
 	SERVER_PORT          int           `envconfig:"SERVER_PORT"`
+	SERVER_GRPC_PORT     int           `envconfig:"SERVER_GRPC_PORT" default:"0"`
 	SERVER_IDLE_TIMEOUT  time.Duration `envconfig:"SERVER_IDLE_TIMEOUT" default:"5m"`
 	SERVER_READ_TIMEOUT  time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"10s"`
 	SERVER_WRITE_TIMEOUT time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"10s"`
+
+	SERVER_SHUTDOWN_TIMEOUT time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"15s"`
+	SERVER_SESSION_COOKIE   string        `envconfig:"SERVER_SESSION_COOKIE" default:"session_id"`
 }
 
 type Postgres struct {
@@ -28,8 +40,55 @@ type Postgres struct {
 	POSTGRES_PORT     int    `envconfig:"POSTGRES_PORT"`
 	POSTGRES_NAME     string `envconfig:"POSTGRES_NAME"`
 
-	POSTGRES_SSLMODE      string `envconfig:"POSTGRES_SSLMODE"`
-	POSTGRES_MAX_CONNECTS int    `envconfig:"POSTGRES_MAX_CONNECTS" default:"10"`
+	// disable, allow, prefer, require, verify-ca или verify-full
+	POSTGRES_SSLMODE     string `envconfig:"POSTGRES_SSLMODE"`
+	POSTGRES_SSLROOTCERT string `envconfig:"POSTGRES_SSLROOTCERT"`
+	POSTGRES_SSLCERT     string `envconfig:"POSTGRES_SSLCERT"`
+	POSTGRES_SSLKEY      string `envconfig:"POSTGRES_SSLKEY"`
+
+	POSTGRES_APPLICATION_NAME string `envconfig:"POSTGRES_APPLICATION_NAME" default:"auth_session"`
+
+	POSTGRES_MAX_CONNECTS        int           `envconfig:"POSTGRES_MAX_CONNECTS" default:"10"`
+	POSTGRES_MIN_CONNECTS        int           `envconfig:"POSTGRES_MIN_CONNECTS" default:"0"`
+	POSTGRES_MAX_CONN_LIFETIME   time.Duration `envconfig:"POSTGRES_MAX_CONN_LIFETIME" default:"1h"`
+	POSTGRES_MAX_CONN_IDLE_TIME  time.Duration `envconfig:"POSTGRES_MAX_CONN_IDLE_TIME" default:"30m"`
+	POSTGRES_HEALTH_CHECK_PERIOD time.Duration `envconfig:"POSTGRES_HEALTH_CHECK_PERIOD" default:"1m"`
+	POSTGRES_CONNECT_TIMEOUT     time.Duration `envconfig:"POSTGRES_CONNECT_TIMEOUT" default:"5s"`
+
+	// Проверка соединения при старте с экспоненциальной задержкой между попытками
+	POSTGRES_PING_ATTEMPTS int           `envconfig:"POSTGRES_PING_ATTEMPTS" default:"5"`
+	POSTGRES_PING_BACKOFF  time.Duration `envconfig:"POSTGRES_PING_BACKOFF" default:"1s"`
+}
+
+type Redis struct {
+	// standalone, sentinel или cluster
+	REDIS_MODE string `envconfig:"REDIS_MODE" default:"standalone"`
+	// Для sentinel - адреса сентинелов, для cluster - начальные узлы
+	REDIS_ADDRS    []string `envconfig:"REDIS_ADDRS" default:"localhost:6379"`
+	REDIS_USERNAME string   `envconfig:"REDIS_USERNAME"`
+	REDIS_PASSWORD string   `envconfig:"REDIS_PASSWORD"`
+	REDIS_DB       int      `envconfig:"REDIS_DB" default:"0"`
+
+	REDIS_SENTINEL_MASTER   string `envconfig:"REDIS_SENTINEL_MASTER"`
+	REDIS_SENTINEL_USERNAME string `envconfig:"REDIS_SENTINEL_USERNAME"`
+	REDIS_SENTINEL_PASSWORD string `envconfig:"REDIS_SENTINEL_PASSWORD"`
+
+	REDIS_TLS                      bool   `envconfig:"REDIS_TLS" default:"false"`
+	REDIS_TLS_CA_FILE              string `envconfig:"REDIS_TLS_CA_FILE"`
+	REDIS_TLS_CERT_FILE            string `envconfig:"REDIS_TLS_CERT_FILE"`
+	REDIS_TLS_KEY_FILE             string `envconfig:"REDIS_TLS_KEY_FILE"`
+	REDIS_TLS_SERVER_NAME          string `envconfig:"REDIS_TLS_SERVER_NAME"`
+	REDIS_TLS_INSECURE_SKIP_VERIFY bool   `envconfig:"REDIS_TLS_INSECURE_SKIP_VERIFY" default:"false"`
+
+	// 0 оставляет значения go-redis по умолчанию
+	REDIS_POOL_SIZE      int `envconfig:"REDIS_POOL_SIZE" default:"0"`
+	REDIS_MIN_IDLE_CONNS int `envconfig:"REDIS_MIN_IDLE_CONNS" default:"0"`
+	REDIS_MAX_RETRIES    int `envconfig:"REDIS_MAX_RETRIES" default:"3"`
+
+	REDIS_DIAL_TIMEOUT  time.Duration `envconfig:"REDIS_DIAL_TIMEOUT" default:"5s"`
+	REDIS_READ_TIMEOUT  time.Duration `envconfig:"REDIS_READ_TIMEOUT" default:"3s"`
+	REDIS_WRITE_TIMEOUT time.Duration `envconfig:"REDIS_WRITE_TIMEOUT" default:"3s"`
+	REDIS_POOL_TIMEOUT  time.Duration `envconfig:"REDIS_POOL_TIMEOUT" default:"4s"`
 }
 
 type Session struct {
@@ -43,6 +102,62 @@ type Session struct {
 
 	SESSION_EXP_LONG        time.Duration `envconfig:"SESSION_EXP_LONG" default:"24h"`
 	SESSION_REFRESH_EXPLONG time.Duration `envconfig:"SESSION_REFRESH_EXPLONG" default:"8766h"`
+
+	SESSION_SLIDING                 bool          `envconfig:"SESSION_SLIDING" default:"false"`
+	SESSION_ACTIVITY_FLUSH_INTERVAL time.Duration `envconfig:"SESSION_ACTIVITY_FLUSH_INTERVAL" default:"1m"`
+
+	// Сверку запускает только cmd/reconciler. Режим restore для строк без
+	// ключа может вернуть в Redis сессию, отзыв которой не дошел до PostgreSQL
+	SESSION_RECONCILE_INTERVAL         time.Duration `envconfig:"SESSION_RECONCILE_INTERVAL" default:"10m"`
+	SESSION_RECONCILE_BATCH_SIZE       int           `envconfig:"SESSION_RECONCILE_BATCH_SIZE" default:"500"`
+	SESSION_RECONCILE_GRACE            time.Duration `envconfig:"SESSION_RECONCILE_GRACE" default:"10s"`
+	SESSION_RECONCILE_POSTGRES_ORPHANS string        `envconfig:"SESSION_RECONCILE_POSTGRES_ORPHANS" default:"delete"`
+
+	SESSION_CLEANUP_MODE       string        `envconfig:"SESSION_CLEANUP_MODE" default:"pg_cron"`
+	SESSION_CLEANUP_INTERVAL   time.Duration `envconfig:"SESSION_CLEANUP_INTERVAL" default:"15m"`
+	SESSION_CLEANUP_BATCH_SIZE int           `envconfig:"SESSION_CLEANUP_BATCH_SIZE" default:"1000"`
+	// Часовой пояс, в котором сервис пишет время сессий. Пустое значение
+	// означает пояс процесса (TZ или /etc/localtime), если его не удается
+	// определить, используется UTC
+	SESSION_CLEANUP_TIME_ZONE string `envconfig:"SESSION_CLEANUP_TIME_ZONE"`
+}
+
+type AccessToken struct {
+	ACCESS_TOKEN_ENABLED bool          `envconfig:"ACCESS_TOKEN_ENABLED" default:"false"`
+	ACCESS_TOKEN_TTL     time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"5m"`
+	ACCESS_TOKEN_ISSUER  string        `envconfig:"ACCESS_TOKEN_ISSUER" default:"auth_session"`
+	// PEM-файлы закрытых ключей Ed25519 или P-256: первым подписываем,
+	// остальные публикуем в JWKS, пока не истекут выпущенные ими токены
+	ACCESS_TOKEN_KEY_FILES []string `envconfig:"ACCESS_TOKEN_KEY_FILES"`
+}
+
+type Otp struct {
+	OTP_LENGTH       int           `envconfig:"OTP_LENGTH" default:"6"`
+	OTP_EXP          time.Duration `envconfig:"OTP_EXP" default:"5m"`
+	OTP_MAX_ATTEMPTS int           `envconfig:"OTP_MAX_ATTEMPTS" default:"5"`
+	// Счетчик неудачных попыток живет дольше кода и не сбрасывается
+	// повторной отправкой, исчерпанный лимит блокирует номер на это время
+	OTP_ATTEMPTS_WINDOW time.Duration `envconfig:"OTP_ATTEMPTS_WINDOW" default:"1h"`
+	OTP_RESEND_COOLDOWN time.Duration `envconfig:"OTP_RESEND_COOLDOWN" default:"1m"`
+	// Ключ HMAC для хешей кодов в Redis, не короче 32 байт
+	OTP_SECRET string `envconfig:"OTP_SECRET"`
+}
+
+type Exolve struct {
+	EXOLVE_BASE_URL      string        `envconfig:"EXOLVE_BASE_URL" default:"https://api.exolve.ru"`
+	EXOLVE_API_KEY       string        `envconfig:"EXOLVE_API_KEY"`
+	EXOLVE_SENDER        string        `envconfig:"EXOLVE_SENDER"`
+	EXOLVE_TIMEOUT       time.Duration `envconfig:"EXOLVE_TIMEOUT" default:"10s"`
+	EXOLVE_MAX_RETRIES   int           `envconfig:"EXOLVE_MAX_RETRIES" default:"3"`
+	EXOLVE_RETRY_BACKOFF time.Duration `envconfig:"EXOLVE_RETRY_BACKOFF" default:"500ms"`
+}
+
+type Messages struct {
+	// Без значения по умолчанию: забытая переменная должна остановить сервер,
+	// а не переключить отправку кодов на консоль
+	MESSAGE_PROVIDERS []string `envconfig:"MESSAGE_PROVIDERS"`
+	MESSAGE_MODE      string   `envconfig:"MESSAGE_MODE" default:"failover"`
+	MESSAGE_FILE_PATH string   `envconfig:"MESSAGE_FILE_PATH" default:"messages.jsonl"`
 }
 
 func Load(filenames ...string) (Config, error) {
@@ -60,19 +175,34 @@ func Get() (Config, error) {
 	return cfg, err
 }
 
+// Собирает URL подключения, экранируя имя пользователя, пароль и параметры
 func PgUrl(cfg Config) string {
-	url := fmt.Sprintf(`postgres://%s:%s@%s:%d/%s`,
-		cfg.POSTGRES_USERNAME,
-		cfg.POSTGRES_PASSWORD,
-		cfg.POSTGRES_HOST,
-		cfg.POSTGRES_PORT,
-		cfg.POSTGRES_NAME,
-	)
-	if cfg.POSTGRES_SSLMODE == "disable" {
-		url = fmt.Sprintf("%s?sslmode=disable",
-			url,
-		)
+	query := url.Values{}
+	params := map[string]string{
+		"sslmode":          cfg.POSTGRES_SSLMODE,
+		"sslrootcert":      cfg.POSTGRES_SSLROOTCERT,
+		"sslcert":          cfg.POSTGRES_SSLCERT,
+		"sslkey":           cfg.POSTGRES_SSLKEY,
+		"application_name": cfg.POSTGRES_APPLICATION_NAME,
+	}
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if cfg.POSTGRES_CONNECT_TIMEOUT > 0 {
+		// connect_timeout задается в целых секундах
+		seconds := int(math.Ceil(cfg.POSTGRES_CONNECT_TIMEOUT.Seconds()))
+		query.Set("connect_timeout", strconv.Itoa(seconds))
+	}
+
+	pgUrl := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.POSTGRES_USERNAME, cfg.POSTGRES_PASSWORD),
+		Host:     net.JoinHostPort(cfg.POSTGRES_HOST, strconv.Itoa(cfg.POSTGRES_PORT)),
+		Path:     "/" + cfg.POSTGRES_NAME,
+		RawQuery: query.Encode(),
 	}
 
-	return url
+	return pgUrl.String()
 }
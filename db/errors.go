@@ -0,0 +1,9 @@
+package db
+
+import "errors"
+
+var (
+	ErrInvalidRedisConfig    = errors.New("invalid redis config")
+	ErrInvalidPostgresConfig = errors.New("invalid postgres config")
+	ErrPostgresUnavailable   = errors.New("postgres unavailable")
+)
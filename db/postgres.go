@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var pgSslModes = map[string]bool{
+	"":            true,
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// Создает пул соединений с настройками из config.Postgres и дожидается,
+// пока PostgreSQL ответит на ping, повторяя попытки с экспоненциальной задержкой
+func NewPgPool(ctx context.Context, cfg config.Config) (*pgxpool.Pool, error) {
+	if !pgSslModes[cfg.POSTGRES_SSLMODE] {
+		return nil, fmt.Errorf("%w: unknown POSTGRES_SSLMODE %q", ErrInvalidPostgresConfig, cfg.POSTGRES_SSLMODE)
+	}
+	if cfg.POSTGRES_MIN_CONNECTS > cfg.POSTGRES_MAX_CONNECTS {
+		return nil, errors.Join(ErrInvalidPostgresConfig, errors.New("POSTGRES_MIN_CONNECTS exceeds POSTGRES_MAX_CONNECTS"))
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(config.PgUrl(cfg))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidPostgresConfig, err)
+	}
+	poolConfig.MaxConns = int32(cfg.POSTGRES_MAX_CONNECTS)
+	poolConfig.MinConns = int32(cfg.POSTGRES_MIN_CONNECTS)
+	poolConfig.MaxConnLifetime = cfg.POSTGRES_MAX_CONN_LIFETIME
+	poolConfig.MaxConnIdleTime = cfg.POSTGRES_MAX_CONN_IDLE_TIME
+	poolConfig.HealthCheckPeriod = cfg.POSTGRES_HEALTH_CHECK_PERIOD
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidPostgresConfig, err)
+	}
+
+	if err := pingPostgres(ctx, pool, cfg.Postgres); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+func pingPostgres(ctx context.Context, pool *pgxpool.Pool, cfg config.Postgres) error {
+	attempts := max(cfg.POSTGRES_PING_ATTEMPTS, 1)
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := cfg.POSTGRES_PING_BACKOFF * time.Duration(1<<(attempt-1))
+			slog.Warn("postgres is not ready, retrying", "attempt", attempt, "delay", delay, "error", lastErr)
+			select {
+			case <-ctx.Done():
+				return errors.Join(ErrPostgresUnavailable, ctx.Err(), lastErr)
+			case <-time.After(delay):
+			}
+		}
+
+		pingCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.POSTGRES_CONNECT_TIMEOUT > 0 {
+			pingCtx, cancel = context.WithTimeout(ctx, cfg.POSTGRES_CONNECT_TIMEOUT)
+		}
+		lastErr = pool.Ping(pingCtx)
+		cancel()
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return errors.Join(ErrPostgresUnavailable, lastErr)
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// Создает клиент Redis в режиме REDIS_MODE. Репозитории принимают
+// redis.UniversalClient и не зависят от выбранного режима
+func NewRedisClient(cfg config.Redis) (redis.UniversalClient, error) {
+	if len(cfg.REDIS_ADDRS) == 0 {
+		return nil, errors.Join(ErrInvalidRedisConfig, errors.New("REDIS_ADDRS is empty"))
+	}
+
+	tlsConfig, err := redisTlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.REDIS_MODE {
+	case RedisModeStandalone:
+		if len(cfg.REDIS_ADDRS) > 1 {
+			return nil, errors.Join(ErrInvalidRedisConfig, errors.New("standalone mode expects a single address"))
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.REDIS_ADDRS[0],
+			Username:     cfg.REDIS_USERNAME,
+			Password:     cfg.REDIS_PASSWORD,
+			DB:           cfg.REDIS_DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.REDIS_POOL_SIZE,
+			MinIdleConns: cfg.REDIS_MIN_IDLE_CONNS,
+			MaxRetries:   cfg.REDIS_MAX_RETRIES,
+			DialTimeout:  cfg.REDIS_DIAL_TIMEOUT,
+			ReadTimeout:  cfg.REDIS_READ_TIMEOUT,
+			WriteTimeout: cfg.REDIS_WRITE_TIMEOUT,
+			PoolTimeout:  cfg.REDIS_POOL_TIMEOUT,
+		}), nil
+	case RedisModeSentinel:
+		if cfg.REDIS_SENTINEL_MASTER == "" {
+			return nil, errors.Join(ErrInvalidRedisConfig, errors.New("REDIS_SENTINEL_MASTER is required in sentinel mode"))
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.REDIS_SENTINEL_MASTER,
+			SentinelAddrs:    cfg.REDIS_ADDRS,
+			SentinelUsername: cfg.REDIS_SENTINEL_USERNAME,
+			SentinelPassword: cfg.REDIS_SENTINEL_PASSWORD,
+			Username:         cfg.REDIS_USERNAME,
+			Password:         cfg.REDIS_PASSWORD,
+			DB:               cfg.REDIS_DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         cfg.REDIS_POOL_SIZE,
+			MinIdleConns:     cfg.REDIS_MIN_IDLE_CONNS,
+			MaxRetries:       cfg.REDIS_MAX_RETRIES,
+			DialTimeout:      cfg.REDIS_DIAL_TIMEOUT,
+			ReadTimeout:      cfg.REDIS_READ_TIMEOUT,
+			WriteTimeout:     cfg.REDIS_WRITE_TIMEOUT,
+			PoolTimeout:      cfg.REDIS_POOL_TIMEOUT,
+		}), nil
+	case RedisModeCluster:
+		// В кластере есть только база 0
+		if cfg.REDIS_DB != 0 {
+			return nil, errors.Join(ErrInvalidRedisConfig, errors.New("cluster mode supports only REDIS_DB=0"))
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.REDIS_ADDRS,
+			Username:     cfg.REDIS_USERNAME,
+			Password:     cfg.REDIS_PASSWORD,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.REDIS_POOL_SIZE,
+			MinIdleConns: cfg.REDIS_MIN_IDLE_CONNS,
+			MaxRetries:   cfg.REDIS_MAX_RETRIES,
+			DialTimeout:  cfg.REDIS_DIAL_TIMEOUT,
+			ReadTimeout:  cfg.REDIS_READ_TIMEOUT,
+			WriteTimeout: cfg.REDIS_WRITE_TIMEOUT,
+			PoolTimeout:  cfg.REDIS_POOL_TIMEOUT,
+		}), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown REDIS_MODE %q", ErrInvalidRedisConfig, cfg.REDIS_MODE)
+	}
+}
+
+func redisTlsConfig(cfg config.Redis) (*tls.Config, error) {
+	if !cfg.REDIS_TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.REDIS_TLS_SERVER_NAME,
+		InsecureSkipVerify: cfg.REDIS_TLS_INSECURE_SKIP_VERIFY,
+	}
+
+	if cfg.REDIS_TLS_CA_FILE != "" {
+		ca, err := os.ReadFile(cfg.REDIS_TLS_CA_FILE)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidRedisConfig, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Join(ErrInvalidRedisConfig, errors.New("no certificates in REDIS_TLS_CA_FILE"))
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Клиентский сертификат для mTLS
+	if cfg.REDIS_TLS_CERT_FILE != "" || cfg.REDIS_TLS_KEY_FILE != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.REDIS_TLS_CERT_FILE, cfg.REDIS_TLS_KEY_FILE)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidRedisConfig, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,69 @@
+package domain
+
+import "errors"
+
+type StartLoginDto struct {
+	Phone string
+}
+
+type VerifyLoginDto struct {
+	Phone string
+	Code  string
+	Tier  SessionTier
+
+	IpAddress  string
+	UserAgent  string
+	DeviceName string
+}
+
+func (dto StartLoginDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateUserPhone(dto.Phone); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
+
+func (dto VerifyLoginDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateUserPhone(dto.Phone); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateOtpCode(dto.Code); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateSessionTier(dto.Tier); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	// Метаданные проверяем до погашения кода, иначе ошибка в них сожжет код
+	if dto.IpAddress != "" {
+		if err := ValidateIpAddress(dto.IpAddress); err != nil {
+			validationErrors = append(validationErrors, err)
+		}
+	}
+	if err := ValidateUserAgent(dto.UserAgent); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateDeviceName(dto.DeviceName); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
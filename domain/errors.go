@@ -8,4 +8,18 @@ var (
 	ErrInvalidUserName  = errors.New("invalid user name")
 	ErrInvalidUserPhone = errors.New("invalid user phone")
 	ErrInvalidUserRole  = errors.New("invalid user role")
+	ErrInvalidOtpCode   = errors.New("invalid otp code")
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrInvalidTier      = errors.New("invalid session tier")
+	ErrInvalidIpAddress = errors.New("invalid ip address")
+	ErrInvalidUserAgent = errors.New("invalid user agent")
+	ErrInvalidDevice    = errors.New("invalid device name")
+
+	ErrInvalidRoles = errors.New("invalid role configuration")
+
+	// Общая для хранилищ сессий и проверяющих, которым не нужны repos
+	ErrSessionNotFound = errors.New("session not found")
+
+	// Временная недоступность внешней зависимости, запрос можно повторить
+	ErrUnavailable = errors.New("service unavailable")
 )
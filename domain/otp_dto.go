@@ -0,0 +1,53 @@
+package domain
+
+import "errors"
+
+type CreateOtpDto struct {
+	Phone string
+	Code  string
+}
+
+type VerifyOtpDto struct {
+	Phone string
+	Code  string
+}
+
+func (dto CreateOtpDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateUserPhone(dto.Phone); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateOtpCode(dto.Code); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
+
+func (dto VerifyOtpDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateUserPhone(dto.Phone); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateOtpCode(dto.Code); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
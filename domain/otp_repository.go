@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+type OtpRepository interface {
+	// RAM only
+	Create(ctx context.Context, dto CreateOtpDto) error
+	Verify(ctx context.Context, dto VerifyOtpDto) error
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+type Role struct {
+	Name  string
+	Level int
+}
+
+// Встроенные роли, на которые опираются проверки доступа в handlers и grpcapi
+const (
+	UserRoleUser    = "user"
+	UserRoleManager = "manager"
+	UserRoleAdmin   = "admin"
+)
+
+var builtinRoles = []Role{
+	{Name: UserRoleUser, Level: 0},
+	{Name: UserRoleManager, Level: 1},
+	{Name: UserRoleAdmin, Level: 2},
+}
+
+// Иерархия ролей загружается из таблицы roles при старте через SetRoles,
+// до загрузки действуют встроенные роли
+var roles = newRoleSet(builtinRoles)
+
+type roleSet struct {
+	mu     sync.RWMutex
+	levels map[string]int
+	names  []string
+}
+
+func newRoleSet(list []Role) *roleSet {
+	s := &roleSet{}
+	s.set(list)
+	return s
+}
+
+func (s *roleSet) set(list []Role) {
+	sorted := append([]Role(nil), list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Level < sorted[j].Level
+	})
+
+	levels := make(map[string]int, len(sorted))
+	names := make([]string, 0, len(sorted))
+	for _, role := range sorted {
+		levels[role.Name] = role.Level
+		names = append(names, role.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.levels = levels
+	s.names = names
+}
+
+// Заменяет иерархию ролей. Встроенные роли обязательны и должны идти
+// в порядке user < manager < admin, уровни ролей не повторяются
+func SetRoles(list []Role) error {
+	nameRegex := regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	levels := make(map[string]int, len(list))
+	levelNames := make(map[int]string, len(list))
+	for _, role := range list {
+		if !nameRegex.MatchString(role.Name) {
+			return fmt.Errorf("%w: invalid role name %q", ErrInvalidRoles, role.Name)
+		}
+		if _, ok := levels[role.Name]; ok {
+			return fmt.Errorf("%w: duplicate role %q", ErrInvalidRoles, role.Name)
+		}
+		// Роли одного уровня покрывали бы друг друга
+		if name, ok := levelNames[role.Level]; ok {
+			return fmt.Errorf("%w: roles %q and %q share level %d", ErrInvalidRoles, name, role.Name, role.Level)
+		}
+		levels[role.Name] = role.Level
+		levelNames[role.Level] = role.Name
+	}
+	for i, role := range builtinRoles {
+		level, ok := levels[role.Name]
+		if !ok {
+			return fmt.Errorf("%w: missing builtin role %q", ErrInvalidRoles, role.Name)
+		}
+		if i > 0 && level <= levels[builtinRoles[i-1].Name] {
+			return fmt.Errorf("%w: role %q must have a higher level than %q", ErrInvalidRoles, role.Name, builtinRoles[i-1].Name)
+		}
+	}
+
+	roles.set(list)
+	return nil
+}
+
+// Роли в порядке возрастания уровня
+func Roles() []Role {
+	roles.mu.RLock()
+	defer roles.mu.RUnlock()
+	list := make([]Role, 0, len(roles.names))
+	for _, name := range roles.names {
+		list = append(list, Role{Name: name, Level: roles.levels[name]})
+	}
+	return list
+}
+
+func RoleLevel(role string) (int, bool) {
+	roles.mu.RLock()
+	defer roles.mu.RUnlock()
+	level, ok := roles.levels[role]
+	return level, ok
+}
+
+// Роль с уровнем не ниже required. Неизвестные роли ничего не покрывают
+func RoleCovers(role, required string) bool {
+	level, ok := RoleLevel(role)
+	if !ok {
+		return false
+	}
+	requiredLevel, ok := RoleLevel(required)
+	return ok && level >= requiredLevel
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetRoles(t *testing.T) {
+	t.Cleanup(func() { roles.set(builtinRoles) })
+
+	tests := []struct {
+		name    string
+		list    []Role
+		wantErr bool
+	}{
+		{"builtin", builtinRoles, false},
+		{"custom role between builtin", []Role{{"user", 0}, {"support", 5}, {"manager", 10}, {"admin", 20}}, false},
+		{"missing builtin", []Role{{"user", 0}, {"admin", 2}}, true},
+		{"duplicate name", []Role{{"user", 0}, {"manager", 1}, {"admin", 2}, {"user", 3}}, true},
+		{"duplicate level", []Role{{"user", 0}, {"manager", 1}, {"support", 1}, {"admin", 2}}, true},
+		{"manager above admin", []Role{{"user", 0}, {"manager", 3}, {"admin", 2}}, true},
+		{"user equals manager", []Role{{"user", 1}, {"manager", 1}, {"admin", 2}}, true},
+		{"invalid name", []Role{{"User", 0}, {"manager", 1}, {"admin", 2}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles.set(builtinRoles)
+			err := SetRoles(tt.list)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRoles) {
+					t.Fatalf("SetRoles: error = %v, want %v", err, ErrInvalidRoles)
+				}
+				if !RoleCovers(UserRoleAdmin, UserRoleManager) || RoleCovers(UserRoleUser, UserRoleManager) {
+					t.Fatal("rejected roles must not replace the hierarchy")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetRoles: %v", err)
+			}
+		})
+	}
+}
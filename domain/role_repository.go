@@ -0,0 +1,7 @@
+package domain
+
+import "context"
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]Role, error)
+}
@@ -7,17 +7,35 @@ import (
 type CreateSessionDto struct {
 	UserId      string
 	SessionRole string
+	Tier        SessionTier
+
+	IpAddress  string
+	UserAgent  string
+	DeviceName string
 }
 
 type FindSessionDto struct {
 	Id string
 }
 
+type FindSessionByTokenDto struct {
+	Token string
+}
+
 type FindSessionWithRoleDto struct {
 	Id          string
 	SessionRole string
 }
 
+type FindUserSessionDto struct {
+	UserId string
+	Id     string
+}
+
+type RefreshSessionDto struct {
+	RefreshToken string
+}
+
 func (dto CreateSessionDto) Validate() error {
 	var validationErrors []error
 
@@ -27,6 +45,20 @@ func (dto CreateSessionDto) Validate() error {
 	if err := ValidateUserRole(dto.SessionRole); err != nil {
 		validationErrors = append(validationErrors, err)
 	}
+	if err := ValidateSessionTier(dto.Tier); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if dto.IpAddress != "" {
+		if err := ValidateIpAddress(dto.IpAddress); err != nil {
+			validationErrors = append(validationErrors, err)
+		}
+	}
+	if err := ValidateUserAgent(dto.UserAgent); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateDeviceName(dto.DeviceName); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
 
 	if len(validationErrors) > 0 {
 		return errors.Join(
@@ -55,6 +87,23 @@ func (dto FindSessionDto) Validate() error {
 	return nil
 }
 
+func (dto FindSessionByTokenDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateToken(dto.Token); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
+
 func (dto FindSessionWithRoleDto) Validate() error {
 	var validationErrors []error
 
@@ -74,3 +123,40 @@ func (dto FindSessionWithRoleDto) Validate() error {
 
 	return nil
 }
+
+func (dto FindUserSessionDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateUuid(dto.UserId); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if err := ValidateUuid(dto.Id); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
+
+func (dto RefreshSessionDto) Validate() error {
+	var validationErrors []error
+
+	if err := ValidateToken(dto.RefreshToken); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.Join(
+			ErrValidationError,
+			errors.Join(validationErrors...),
+		)
+	}
+
+	return nil
+}
@@ -2,17 +2,50 @@ package domain
 
 import "time"
 
+type SessionTier string
+
+const (
+	SessionTierShort  SessionTier = "short"
+	SessionTierNormal SessionTier = "normal"
+	SessionTierLong   SessionTier = "long"
+)
+
+var ValidSessionTiers = map[SessionTier]bool{
+	SessionTierShort:  true,
+	SessionTierNormal: true,
+	SessionTierLong:   true,
+}
+
 type SessionInfo struct {
-	UserId   string
-	UserName string
-	UserRole string
+	SessionId string
+	UserId    string
+	UserName  string
+	UserRole  string
 }
 
 type Session struct {
-	Id string
-
 	SessionInfo
 
+	Tier             SessionTier
 	ExpiresAt        time.Time
 	RefreshExpiresAt time.Time
+
+	CreatedAt   time.Time
+	LastSeenAt  time.Time
+	IpAddress   string
+	UserAgent   string
+	DeviceLabel string
+	DeviceName  string
+}
+
+type SessionTokens struct {
+	SessionInfo
+
+	// Секрет для Authorization: Bearer, SessionId токеном не является
+	SessionToken string
+	RefreshToken string
+
+	// Заполняются, только если включены подписанные access-токены
+	AccessToken          string
+	AccessTokenExpiresAt time.Time
 }
@@ -1,17 +1,20 @@
 package domain
 
-import (
-	"context"
-	"time"
-)
+import "context"
 
 type SessionRepository interface {
 	// SD + RAM
-	Create(ctx context.Context, dto CreateSessionDto, ttl, refreshTtl time.Duration) (string, error)
+	Create(ctx context.Context, dto CreateSessionDto) (SessionTokens, error)
+	CreateWithLimit(ctx context.Context, dto CreateSessionDto) (SessionTokens, error)
 	GetUserSessionCount(ctx context.Context, dto FindSessionWithRoleDto) (int, error)
 	DeleteOldestUserSession(ctx context.Context, dto FindSessionWithRoleDto) error
+	Refresh(ctx context.Context, dto RefreshSessionDto) (SessionTokens, error)
 	Delete(ctx context.Context, dto FindSessionDto) error
+	ListUserSessions(ctx context.Context, dto FindUserDto) ([]Session, error)
+	RevokeUserSession(ctx context.Context, dto FindUserSessionDto) error
+	RevokeAllUserSessions(ctx context.Context, dto FindUserDto) error
+	RevokeOtherUserSessions(ctx context.Context, dto FindUserSessionDto) error
 
 	// RAM only
-	FindSessionInfo(ctx context.Context, dto FindSessionDto) (SessionInfo, error)
+	FindSessionInfo(ctx context.Context, dto FindSessionByTokenDto) (SessionInfo, error)
 }
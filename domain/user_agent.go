@@ -0,0 +1,66 @@
+package domain
+
+import "strings"
+
+type userAgentRule struct {
+	token string
+	name  string
+}
+
+// Порядок важен: Edge и Opera содержат "Chrome", Chrome содержит "Safari"
+var userAgentBrowsers = []userAgentRule{
+	{"YaBrowser", "Yandex Browser"},
+	{"Edg", "Edge"},
+	{"OPR", "Opera"},
+	{"Firefox", "Firefox"},
+	{"Chrome", "Chrome"},
+	{"Safari", "Safari"},
+	{"okhttp", "Android App"},
+	{"CFNetwork", "iOS App"},
+}
+
+var userAgentSystems = []userAgentRule{
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Возвращает метку вида "Chrome on Android (mobile)" для списка сессий
+func ParseUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+
+	browser := matchUserAgent(userAgent, userAgentBrowsers)
+	system := matchUserAgent(userAgent, userAgentSystems)
+	device := "desktop"
+	switch {
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet"):
+		device = "tablet"
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "Android"):
+		device = "mobile"
+	}
+
+	switch {
+	case browser == "" && system == "":
+		return "Unknown device"
+	case browser == "":
+		return system + " (" + device + ")"
+	case system == "":
+		return browser + " (" + device + ")"
+	default:
+		return browser + " on " + system + " (" + device + ")"
+	}
+}
+
+func matchUserAgent(userAgent string, rules []userAgentRule) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule.token) {
+			return rule.name
+		}
+	}
+	return ""
+}
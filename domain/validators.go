@@ -2,7 +2,11 @@ package domain
 
 import (
 	"fmt"
+	"net/netip"
 	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -38,9 +42,64 @@ func ValidateUserPhone(phone string) error {
 }
 
 func ValidateUserRole(role string) error {
+	if _, ok := RoleLevel(role); !ok {
+		var names []string
+		for _, role := range Roles() {
+			names = append(names, strconv.Quote(role.Name))
+		}
+		return fmt.Errorf(`%w expected one of: %s`, ErrInvalidUserRole, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func ValidateSessionTier(tier SessionTier) error {
+	if !ValidSessionTiers[tier] {
+		return fmt.Errorf(`%w expected one of: "short", "normal", "long"`, ErrInvalidTier)
+	}
+	return nil
+}
+
+func ValidateIpAddress(ip string) error {
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return fmt.Errorf(`%w expected IPv4 or IPv6 address`, ErrInvalidIpAddress)
+	}
+	return nil
+}
+
+func ValidateUserAgent(userAgent string) error {
+	if utf8.RuneCountInString(userAgent) > 512 {
+		return fmt.Errorf(`%w expected at most 512 characters`, ErrInvalidUserAgent)
+	}
+	return nil
+}
+
+func ValidateDeviceName(name string) error {
+	if utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf(`%w expected at most 100 characters`, ErrInvalidDevice)
+	}
+	return nil
+}
+
+func ValidateToken(token string) error {
+	tokenRegex := regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+	if !tokenRegex.MatchString(token) {
+		return fmt.Errorf(`%w expected ^[A-Za-z0-9_-]{43}$`, ErrInvalidToken)
+	}
+	return nil
+}
+
+// Длина, при которой сгенерированный код пройдет ValidateOtpCode
+func ValidateOtpLength(length int) error {
+	if length < 4 || length > 8 {
+		return fmt.Errorf(`%w expected OTP_LENGTH from 4 to 8`, ErrInvalidOtpCode)
+	}
+	return nil
+}
 
-	if !ValidUserRoles[role] {
-		return fmt.Errorf(`%w expected one of: "user", "admin", "manager"`, ErrInvalidUserRole)
+func ValidateOtpCode(code string) error {
+	codeRegex := regexp.MustCompile(`^\d{4,8}$`)
+	if !codeRegex.MatchString(code) {
+		return fmt.Errorf(`%w expected ^\d{4,8}$`, ErrInvalidOtpCode)
 	}
 	return nil
 }
@@ -0,0 +1,121 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+	"github.com/Grubiha/auth_session/repos"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const errorDomain = "auth_session"
+
+type errorMapping struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// Порядок важен: первая совпавшая ошибка определяет код
+var errorMappings = []errorMapping{
+	{domain.ErrValidationError, codes.InvalidArgument, "VALIDATION_ERROR"},
+	{middleware.ErrUnauthorized, codes.Unauthenticated, "UNAUTHENTICATED"},
+	{middleware.ErrForbidden, codes.PermissionDenied, "FORBIDDEN"},
+	{repos.ErrRoleMistmatch, codes.PermissionDenied, "ROLE_MISMATCH"},
+	{repos.ErrUserNotFound, codes.NotFound, "USER_NOT_FOUND"},
+	{repos.ErrSessionNotFound, codes.NotFound, "SESSION_NOT_FOUND"},
+	{repos.ErrSessionExpired, codes.Unauthenticated, "SESSION_EXPIRED"},
+	{repos.ErrRefreshTokenReused, codes.Unauthenticated, "REFRESH_TOKEN_REUSED"},
+	{repos.ErrUniqueViolation, codes.AlreadyExists, "UNIQUE_VIOLATION"},
+	{repos.ErrOtpNotFound, codes.Unauthenticated, "OTP_NOT_FOUND"},
+	{repos.ErrOtpMismatch, codes.Unauthenticated, "OTP_MISMATCH"},
+	{repos.ErrOtpAttemptsExceeded, codes.ResourceExhausted, "OTP_ATTEMPTS_EXCEEDED"},
+	{repos.ErrOtpResendCooldown, codes.ResourceExhausted, "OTP_RESEND_COOLDOWN"},
+	{domain.ErrUnavailable, codes.Unavailable, "UNAVAILABLE"},
+}
+
+var validationFields = map[error]string{
+	domain.ErrInvalidUuid:      "id",
+	domain.ErrInvalidUserName:  "name",
+	domain.ErrInvalidUserPhone: "phone",
+	domain.ErrInvalidUserRole:  "role",
+	domain.ErrInvalidOtpCode:   "code",
+	domain.ErrInvalidToken:     "refresh_token",
+	domain.ErrInvalidTier:      "tier",
+	domain.ErrInvalidIpAddress: "ip_address",
+	domain.ErrInvalidUserAgent: "user_agent",
+	domain.ErrInvalidDevice:    "device_name",
+}
+
+// Переводит ошибки domain и repos в статус gRPC с ErrorInfo,
+// для ошибок валидации добавляет BadRequest с нарушениями по полям
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.err) {
+			continue
+		}
+
+		details := []protoadapt.MessageV1{
+			&errdetails.ErrorInfo{
+				Reason: mapping.reason,
+				Domain: errorDomain,
+			},
+		}
+		if mapping.code == codes.InvalidArgument {
+			details = append(details, badRequest(err))
+		}
+
+		st, detailsErr := status.New(mapping.code, mapping.err.Error()).WithDetails(details...)
+		if detailsErr != nil {
+			return status.Error(mapping.code, mapping.err.Error())
+		}
+		return st.Err()
+	}
+
+	// Ошибки хранилищ и прочие внутренние ошибки не раскрываем клиенту
+	return status.Error(codes.Internal, "internal error")
+}
+
+func badRequest(err error) *errdetails.BadRequest {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, leaf := range leafErrors(err) {
+		if leaf == domain.ErrValidationError {
+			continue
+		}
+		violation := &errdetails.BadRequest_FieldViolation{
+			Description: leaf.Error(),
+		}
+		for fieldErr, field := range validationFields {
+			if errors.Is(leaf, fieldErr) {
+				violation.Field = field
+				break
+			}
+		}
+		violations = append(violations, violation)
+	}
+	return &errdetails.BadRequest{FieldViolations: violations}
+}
+
+// Раскрывает errors.Join до отдельных ошибок
+func leafErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var leaves []error
+	for _, child := range joined.Unwrap() {
+		leaves = append(leaves, leafErrors(child)...)
+	}
+	return leaves
+}
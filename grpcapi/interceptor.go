@@ -0,0 +1,88 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Grubiha/auth_session/api/authv1"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Проверяет сессию из метаданных "authorization: Bearer <session_token>" и кладет
+// domain.SessionInfo в контекст, доступный через middleware.SessionFromContext.
+// Методы из publicMethods пропускаются без проверки
+func NewAuthInterceptor(sessions middleware.SessionFinder, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		token := bearerToken(ctx)
+		if token == "" {
+			return nil, statusError(middleware.ErrUnauthorized)
+		}
+
+		sessionInfo, err := sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: token})
+		if err != nil {
+			if errors.Is(err, domain.ErrValidationError) || errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrSessionNotFound) {
+				err = middleware.ErrUnauthorized
+			}
+			return nil, statusError(err)
+		}
+
+		return handler(middleware.ContextWithSession(ctx, sessionInfo.SessionId, sessionInfo), req)
+	}
+}
+
+// Проверяет сессии через удаленный AuthService, для сервисов без доступа к Redis
+type RemoteSessionFinder struct {
+	client authv1.AuthServiceClient
+}
+
+func NewRemoteSessionFinder(client authv1.AuthServiceClient) *RemoteSessionFinder {
+	return &RemoteSessionFinder{
+		client: client,
+	}
+}
+
+func (f *RemoteSessionFinder) FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error) {
+	info, err := f.client.ValidateSession(ctx, &authv1.ValidateSessionRequest{SessionToken: dto.Token})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound, codes.Unauthenticated, codes.InvalidArgument:
+			return domain.SessionInfo{}, domain.ErrSessionNotFound
+		default:
+			return domain.SessionInfo{}, err
+		}
+	}
+
+	return domain.SessionInfo{
+		SessionId: info.GetSessionId(),
+		UserId:    info.GetUserId(),
+		UserName:  info.GetUserName(),
+		UserRole:  info.GetUserRole(),
+	}, nil
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := middleware.BearerToken(value); ok {
+			return token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,275 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/Grubiha/auth_session/api/authv1"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+	"github.com/Grubiha/auth_session/usecases"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var PublicMethods = []string{
+	authv1.AuthService_StartLogin_FullMethodName,
+	authv1.AuthService_VerifyLogin_FullMethodName,
+	authv1.AuthService_Refresh_FullMethodName,
+	authv1.AuthService_ValidateSession_FullMethodName,
+}
+
+type Server struct {
+	authv1.UnimplementedAuthServiceServer
+
+	auth     *usecases.AuthUsecase
+	sessions *usecases.SessionManageUsecase
+	users    *usecases.UserManageUsecase
+}
+
+func NewServer(
+	auth *usecases.AuthUsecase,
+	sessions *usecases.SessionManageUsecase,
+	users *usecases.UserManageUsecase,
+) *Server {
+	return &Server{
+		auth:     auth,
+		sessions: sessions,
+		users:    users,
+	}
+}
+
+// Создает grpc.Server с зарегистрированным AuthService и проверкой сессий
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		NewAuthInterceptor(s.auth, PublicMethods...),
+	))
+	server := grpc.NewServer(opts...)
+	authv1.RegisterAuthServiceServer(server, s)
+	return server
+}
+
+func (s *Server) StartLogin(ctx context.Context, req *authv1.StartLoginRequest) (*authv1.StartLoginResponse, error) {
+	err := s.auth.StartLogin(ctx, domain.StartLoginDto{
+		Phone: req.GetPhone(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.StartLoginResponse{}, nil
+}
+
+func (s *Server) VerifyLogin(ctx context.Context, req *authv1.VerifyLoginRequest) (*authv1.SessionTokens, error) {
+	tier := domain.SessionTier(req.GetTier())
+	if tier == "" {
+		tier = domain.SessionTierNormal
+	}
+
+	var ipAddress string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ipAddress = addrHost(p.Addr.String())
+	}
+
+	tokens, err := s.auth.VerifyLogin(ctx, domain.VerifyLoginDto{
+		Phone:      req.GetPhone(),
+		Code:       req.GetCode(),
+		Tier:       tier,
+		IpAddress:  ipAddress,
+		UserAgent:  userAgent(ctx),
+		DeviceName: req.GetDeviceName(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return sessionTokens(tokens), nil
+}
+
+func (s *Server) Refresh(ctx context.Context, req *authv1.RefreshRequest) (*authv1.SessionTokens, error) {
+	tokens, err := s.auth.Refresh(ctx, domain.RefreshSessionDto{
+		RefreshToken: req.GetRefreshToken(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return sessionTokens(tokens), nil
+}
+
+func (s *Server) Logout(ctx context.Context, req *authv1.LogoutRequest) (*authv1.LogoutResponse, error) {
+	sessionId, _ := middleware.SessionIdFromContext(ctx)
+	if err := s.auth.Logout(ctx, domain.FindSessionDto{Id: sessionId}); err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.LogoutResponse{}, nil
+}
+
+func (s *Server) ValidateSession(ctx context.Context, req *authv1.ValidateSessionRequest) (*authv1.SessionInfo, error) {
+	info, err := s.auth.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: req.GetSessionToken()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.SessionInfo{
+		UserId:    info.UserId,
+		UserName:  info.UserName,
+		UserRole:  info.UserRole,
+		SessionId: info.SessionId,
+	}, nil
+}
+
+func (s *Server) ListSessions(ctx context.Context, req *authv1.ListSessionsRequest) (*authv1.ListSessionsResponse, error) {
+	currentId, _ := middleware.SessionIdFromContext(ctx)
+	current, _ := middleware.SessionFromContext(ctx)
+	sessions, err := s.sessions.List(ctx, domain.FindUserDto{Id: current.UserId})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &authv1.ListSessionsResponse{
+		Sessions: make([]*authv1.Session, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, &authv1.Session{
+			Id:               session.SessionId,
+			Current:          session.SessionId == currentId,
+			SessionRole:      session.UserRole,
+			Tier:             string(session.Tier),
+			ExpiresAt:        timestamppb.New(session.ExpiresAt),
+			RefreshExpiresAt: timestamppb.New(session.RefreshExpiresAt),
+			CreatedAt:        timestamppb.New(session.CreatedAt),
+			LastSeenAt:       timestamppb.New(session.LastSeenAt),
+			IpAddress:        session.IpAddress,
+			UserAgent:        session.UserAgent,
+			DeviceLabel:      session.DeviceLabel,
+			DeviceName:       session.DeviceName,
+		})
+	}
+	return response, nil
+}
+
+func (s *Server) RevokeSession(ctx context.Context, req *authv1.RevokeSessionRequest) (*authv1.RevokeSessionResponse, error) {
+	current, _ := middleware.SessionFromContext(ctx)
+	err := s.sessions.Revoke(ctx, domain.FindUserSessionDto{
+		UserId: current.UserId,
+		Id:     req.GetSessionId(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.RevokeSessionResponse{}, nil
+}
+
+func (s *Server) RevokeAllSessions(ctx context.Context, req *authv1.RevokeAllSessionsRequest) (*authv1.RevokeAllSessionsResponse, error) {
+	current, _ := middleware.SessionFromContext(ctx)
+	if err := s.sessions.RevokeAll(ctx, domain.FindUserDto{Id: current.UserId}); err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.RevokeAllSessionsResponse{}, nil
+}
+
+func (s *Server) RevokeOtherSessions(ctx context.Context, req *authv1.RevokeOtherSessionsRequest) (*authv1.RevokeOtherSessionsResponse, error) {
+	currentId, _ := middleware.SessionIdFromContext(ctx)
+	current, _ := middleware.SessionFromContext(ctx)
+	err := s.sessions.RevokeOthers(ctx, domain.FindUserSessionDto{
+		UserId: current.UserId,
+		Id:     currentId,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.RevokeOtherSessionsResponse{}, nil
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *authv1.CreateUserRequest) (*authv1.CreateUserResponse, error) {
+	if !middleware.HasRole(ctx, domain.UserRoleAdmin) {
+		return nil, statusError(middleware.ErrForbidden)
+	}
+
+	id, err := s.users.Create(ctx, domain.CreateUserDto{
+		Name:  req.GetName(),
+		Phone: req.GetPhone(),
+		Role:  req.Role,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.CreateUserResponse{Id: id}, nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *authv1.GetUserRequest) (*authv1.User, error) {
+	current, _ := middleware.SessionFromContext(ctx)
+	if req.GetId() != current.UserId && !middleware.HasRole(ctx, domain.UserRoleManager) {
+		return nil, statusError(middleware.ErrForbidden)
+	}
+
+	user, err := s.users.Find(ctx, domain.FindUserDto{Id: req.GetId()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return userMessage(user), nil
+}
+
+func (s *Server) GetUserByPhone(ctx context.Context, req *authv1.GetUserByPhoneRequest) (*authv1.User, error) {
+	if !middleware.HasRole(ctx, domain.UserRoleManager) {
+		return nil, statusError(middleware.ErrForbidden)
+	}
+
+	user, err := s.users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: req.GetPhone()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return userMessage(user), nil
+}
+
+func (s *Server) UpdateUser(ctx context.Context, req *authv1.UpdateUserRequest) (*authv1.UpdateUserResponse, error) {
+	// Свой профиль можно менять без смены роли и телефона, чужой только
+	// администратору. Телефон служит логином, а владение новым номером
+	// не подтверждено, поэтому его тоже меняет только администратор
+	current, _ := middleware.SessionFromContext(ctx)
+	isAdmin := middleware.HasRole(ctx, domain.UserRoleAdmin)
+	if (req.GetId() != current.UserId || req.Role != nil || req.Phone != nil) && !isAdmin {
+		return nil, statusError(middleware.ErrForbidden)
+	}
+
+	err := s.users.Update(ctx, domain.UpdateUserDto{
+		Id:    req.GetId(),
+		Name:  req.Name,
+		Phone: req.Phone,
+		Role:  req.Role,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.UpdateUserResponse{}, nil
+}
+
+func (s *Server) DeleteUser(ctx context.Context, req *authv1.DeleteUserRequest) (*authv1.DeleteUserResponse, error) {
+	if !middleware.HasRole(ctx, domain.UserRoleAdmin) {
+		return nil, statusError(middleware.ErrForbidden)
+	}
+
+	if err := s.users.Delete(ctx, domain.FindUserDto{Id: req.GetId()}); err != nil {
+		return nil, statusError(err)
+	}
+	return &authv1.DeleteUserResponse{}, nil
+}
+
+func sessionTokens(tokens domain.SessionTokens) *authv1.SessionTokens {
+	response := &authv1.SessionTokens{
+		SessionId:    tokens.SessionId,
+		SessionToken: tokens.SessionToken,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+	}
+	if tokens.AccessToken != "" {
+		response.AccessTokenExpiresAt = timestamppb.New(tokens.AccessTokenExpiresAt)
+	}
+	return response
+}
+
+func userMessage(user domain.User) *authv1.User {
+	return &authv1.User{
+		Id:    user.Id,
+		Name:  user.Name,
+		Phone: user.Phone,
+		Role:  user.Role,
+	}
+}
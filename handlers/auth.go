@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+)
+
+type startLoginRequest struct {
+	Phone string `json:"phone"`
+}
+
+type verifyLoginRequest struct {
+	Phone      string `json:"phone"`
+	Code       string `json:"code"`
+	Tier       string `json:"tier"`
+	DeviceName string `json:"device_name"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type sessionTokensResponse struct {
+	SessionId    string `json:"session_id"`
+	SessionToken string `json:"session_token"`
+	RefreshToken string `json:"refresh_token"`
+
+	AccessToken          string     `json:"access_token,omitempty"`
+	AccessTokenExpiresAt *time.Time `json:"access_token_expires_at,omitempty"`
+}
+
+type sessionInfoResponse struct {
+	SessionId string `json:"session_id"`
+	UserId    string `json:"user_id"`
+	UserName  string `json:"user_name"`
+	UserRole  string `json:"user_role"`
+}
+
+func (h *Handler) startLogin(w http.ResponseWriter, r *http.Request) {
+	var request startLoginRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err := h.auth.StartLogin(r.Context(), domain.StartLoginDto{
+		Phone: request.Phone,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) verifyLogin(w http.ResponseWriter, r *http.Request) {
+	var request verifyLoginRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tier := domain.SessionTier(request.Tier)
+	if tier == "" {
+		tier = domain.SessionTierNormal
+	}
+
+	tokens, err := h.auth.VerifyLogin(r.Context(), domain.VerifyLoginDto{
+		Phone:      request.Phone,
+		Code:       request.Code,
+		Tier:       tier,
+		IpAddress:  clientIp(r),
+		UserAgent:  r.UserAgent(),
+		DeviceName: request.DeviceName,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSessionTokensResponse(tokens))
+}
+
+func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
+	var request refreshRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.auth.Refresh(r.Context(), domain.RefreshSessionDto{
+		RefreshToken: request.RefreshToken,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSessionTokensResponse(tokens))
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	sessionId, _ := middleware.SessionIdFromContext(r.Context())
+	if err := h.auth.Logout(r.Context(), domain.FindSessionDto{Id: sessionId}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) me(w http.ResponseWriter, r *http.Request) {
+	sessionId, _ := middleware.SessionIdFromContext(r.Context())
+	session, _ := middleware.SessionFromContext(r.Context())
+	writeJSON(w, http.StatusOK, sessionInfoResponse{
+		SessionId: sessionId,
+		UserId:    session.UserId,
+		UserName:  session.UserName,
+		UserRole:  session.UserRole,
+	})
+}
+
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) {
+	jwks, _ := h.auth.Jwks()
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, jwks)
+}
+
+func newSessionTokensResponse(tokens domain.SessionTokens) sessionTokensResponse {
+	response := sessionTokensResponse{
+		SessionId:    tokens.SessionId,
+		SessionToken: tokens.SessionToken,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+	}
+	if tokens.AccessToken != "" {
+		response.AccessTokenExpiresAt = &tokens.AccessTokenExpiresAt
+	}
+	return response
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+	"github.com/Grubiha/auth_session/repos"
+)
+
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestId string `json:"request_id,omitempty"`
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrValidationError):
+		return http.StatusBadRequest
+	case errors.Is(err, middleware.ErrUnauthorized),
+		errors.Is(err, domain.ErrInvalidToken),
+		errors.Is(err, repos.ErrSessionNotFound),
+		errors.Is(err, repos.ErrSessionExpired),
+		errors.Is(err, repos.ErrRefreshTokenReused),
+		errors.Is(err, repos.ErrOtpNotFound),
+		errors.Is(err, repos.ErrOtpMismatch):
+		return http.StatusUnauthorized
+	case errors.Is(err, middleware.ErrForbidden),
+		errors.Is(err, repos.ErrRoleMistmatch):
+		return http.StatusForbidden
+	case errors.Is(err, repos.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repos.ErrUniqueViolation):
+		return http.StatusConflict
+	case errors.Is(err, repos.ErrOtpAttemptsExceeded),
+		errors.Is(err, repos.ErrOtpResendCooldown):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(err)
+	requestId := RequestIdFromContext(r.Context())
+
+	// Внутренние ошибки не раскрываем клиенту
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "error", err, "request_id", requestId)
+		message = http.StatusText(status)
+	}
+
+	writeJSON(w, status, errorResponse{
+		Error:     message,
+		RequestId: requestId,
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+type requestIdKey struct{}
+
+var requestIdRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Берет идентификатор запроса из заголовка или генерирует новый
+// и возвращает его в ответе
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIdHeader)
+		if !requestIdRegex.MatchString(requestId) {
+			requestId = uuid.NewString()
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+		ctx := context.WithValue(r.Context(), requestIdKey{}, requestId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
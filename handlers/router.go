@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+	"github.com/Grubiha/auth_session/usecases"
+)
+
+type Handler struct {
+	auth     *usecases.AuthUsecase
+	sessions *usecases.SessionManageUsecase
+	users    *usecases.UserManageUsecase
+
+	authMiddleware *middleware.AuthMiddleware
+}
+
+func NewHandler(
+	auth *usecases.AuthUsecase,
+	sessions *usecases.SessionManageUsecase,
+	users *usecases.UserManageUsecase,
+	sessionCookie string,
+) *Handler {
+	return &Handler{
+		auth:           auth,
+		sessions:       sessions,
+		users:          users,
+		authMiddleware: middleware.NewAuthMiddleware(auth, sessionCookie, writeError),
+	}
+}
+
+func (h *Handler) Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	authenticated := func(handler http.HandlerFunc) http.Handler {
+		return h.authMiddleware.Authenticate(handler)
+	}
+	admin := func(handler http.HandlerFunc) http.Handler {
+		return h.authMiddleware.Authenticate(h.authMiddleware.RequireRole(domain.UserRoleAdmin)(handler))
+	}
+
+	mux.HandleFunc("GET /healthz", h.healthz)
+	if _, ok := h.auth.Jwks(); ok {
+		mux.HandleFunc("GET /.well-known/jwks.json", h.jwks)
+	}
+
+	mux.HandleFunc("POST /auth/login/start", h.startLogin)
+	mux.HandleFunc("POST /auth/login/verify", h.verifyLogin)
+	mux.HandleFunc("POST /auth/refresh", h.refresh)
+	mux.Handle("POST /auth/logout", authenticated(h.logout))
+	mux.Handle("GET /auth/me", authenticated(h.me))
+
+	mux.Handle("GET /sessions", authenticated(h.listSessions))
+	mux.Handle("DELETE /sessions", authenticated(h.revokeAllSessions))
+	mux.Handle("DELETE /sessions/others", authenticated(h.revokeOtherSessions))
+	mux.Handle("DELETE /sessions/{id}", authenticated(h.revokeSession))
+
+	mux.Handle("POST /users", admin(h.createUser))
+	mux.Handle("GET /users/{id}", authenticated(h.findUser))
+	mux.Handle("PATCH /users/{id}", authenticated(h.updateUser))
+	mux.Handle("DELETE /users/{id}", admin(h.deleteUser))
+
+	return RequestId(mux)
+}
+
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
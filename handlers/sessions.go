@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+)
+
+type sessionResponse struct {
+	Id               string    `json:"id"`
+	Current          bool      `json:"current"`
+	SessionRole      string    `json:"session_role"`
+	Tier             string    `json:"tier"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+	IpAddress        string    `json:"ip_address"`
+	UserAgent        string    `json:"user_agent"`
+	DeviceLabel      string    `json:"device_label"`
+	DeviceName       string    `json:"device_name"`
+}
+
+func (h *Handler) listSessions(w http.ResponseWriter, r *http.Request) {
+	currentId, _ := middleware.SessionIdFromContext(r.Context())
+	current, _ := middleware.SessionFromContext(r.Context())
+	sessions, err := h.sessions.List(r.Context(), domain.FindUserDto{Id: current.UserId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			Id:               session.SessionId,
+			Current:          session.SessionId == currentId,
+			SessionRole:      session.UserRole,
+			Tier:             string(session.Tier),
+			ExpiresAt:        session.ExpiresAt,
+			RefreshExpiresAt: session.RefreshExpiresAt,
+			CreatedAt:        session.CreatedAt,
+			LastSeenAt:       session.LastSeenAt,
+			IpAddress:        session.IpAddress,
+			UserAgent:        session.UserAgent,
+			DeviceLabel:      session.DeviceLabel,
+			DeviceName:       session.DeviceName,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	current, _ := middleware.SessionFromContext(r.Context())
+	err := h.sessions.Revoke(r.Context(), domain.FindUserSessionDto{
+		UserId: current.UserId,
+		Id:     r.PathValue("id"),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	current, _ := middleware.SessionFromContext(r.Context())
+	if err := h.sessions.RevokeAll(r.Context(), domain.FindUserDto{Id: current.UserId}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	currentId, _ := middleware.SessionIdFromContext(r.Context())
+	current, _ := middleware.SessionFromContext(r.Context())
+	err := h.sessions.RevokeOthers(r.Context(), domain.FindUserSessionDto{
+		UserId: current.UserId,
+		Id:     currentId,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
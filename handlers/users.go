@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/middleware"
+)
+
+type createUserRequest struct {
+	Name  string  `json:"name"`
+	Phone string  `json:"phone"`
+	Role  *string `json:"role"`
+}
+
+type updateUserRequest struct {
+	Name  *string `json:"name"`
+	Phone *string `json:"phone"`
+	Role  *string `json:"role"`
+}
+
+type createUserResponse struct {
+	Id string `json:"id"`
+}
+
+type userResponse struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+	Role  string `json:"role"`
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var request createUserRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	id, err := h.users.Create(r.Context(), domain.CreateUserDto{
+		Name:  request.Name,
+		Phone: request.Phone,
+		Role:  request.Role,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createUserResponse{Id: id})
+}
+
+func (h *Handler) findUser(w http.ResponseWriter, r *http.Request) {
+	current, _ := middleware.SessionFromContext(r.Context())
+	id := r.PathValue("id")
+	if id != current.UserId && !middleware.HasRole(r.Context(), domain.UserRoleManager) {
+		writeError(w, r, middleware.ErrForbidden)
+		return
+	}
+
+	user, err := h.users.Find(r.Context(), domain.FindUserDto{Id: id})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userResponse{
+		Id:    user.Id,
+		Name:  user.Name,
+		Phone: user.Phone,
+		Role:  user.Role,
+	})
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	var request updateUserRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Свой профиль можно менять без смены роли и телефона, чужой только
+	// администратору. Телефон служит логином, а владение новым номером
+	// не подтверждено, поэтому его тоже меняет только администратор
+	current, _ := middleware.SessionFromContext(r.Context())
+	id := r.PathValue("id")
+	isAdmin := middleware.HasRole(r.Context(), domain.UserRoleAdmin)
+	if (id != current.UserId || request.Role != nil || request.Phone != nil) && !isAdmin {
+		writeError(w, r, middleware.ErrForbidden)
+		return
+	}
+
+	err := h.users.Update(r.Context(), domain.UpdateUserDto{
+		Id:    id,
+		Name:  request.Name,
+		Phone: request.Phone,
+		Role:  request.Role,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.users.Delete(r.Context(), domain.FindUserDto{Id: r.PathValue("id")}); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,13 @@
+package integrations
+
+import "errors"
+
+var (
+	ErrExolveRequestFailed   = errors.New("exolve request failed")
+	ErrExolveInvalidResponse = errors.New("exolve invalid response")
+	ErrExolveBadRequest      = errors.New("exolve bad request")
+	ErrExolveUnauthorized    = errors.New("exolve unauthorized")
+	ErrExolveRateLimited     = errors.New("exolve rate limited")
+	ErrExolveUnavailable     = errors.New("exolve unavailable")
+	ErrExolveInvalidConfig   = errors.New("invalid exolve config")
+)
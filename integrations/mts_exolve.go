@@ -1 +1,234 @@
 package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+)
+
+const (
+	exolveSendSmsPath = "/messaging/v1/SendSMS"
+	exolveGetDlrPath  = "/messaging/v1/GetDlr"
+
+	// Ответы Exolve небольшие, больше не читаем
+	exolveMaxResponseBytes = 1 << 20
+)
+
+type ExolveMessageStatus string
+
+const (
+	ExolveStatusPending     ExolveMessageStatus = "pending"
+	ExolveStatusSent        ExolveMessageStatus = "sent"
+	ExolveStatusDelivered   ExolveMessageStatus = "delivered"
+	ExolveStatusUndelivered ExolveMessageStatus = "undelivered"
+	ExolveStatusUnknown     ExolveMessageStatus = "unknown"
+)
+
+type ExolveClient struct {
+	httpClient *http.Client
+
+	baseUrl string
+	apiKey  string
+	sender  string
+
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+type exolveSendSmsRequest struct {
+	Number      string `json:"number"`
+	Destination string `json:"destination"`
+	Text        string `json:"text"`
+}
+
+type exolveSendSmsResponse struct {
+	MessageId string `json:"message_id"`
+}
+
+type exolveGetDlrRequest struct {
+	MessageId string `json:"message_id"`
+}
+
+type exolveGetDlrResponse struct {
+	Status string `json:"status"`
+}
+
+type exolveErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewExolveClient(cfg config.Exolve) (*ExolveClient, error) {
+	if cfg.EXOLVE_MAX_RETRIES < 0 {
+		return nil, errors.Join(ErrExolveInvalidConfig, errors.New("EXOLVE_MAX_RETRIES must not be negative"))
+	}
+
+	return &ExolveClient{
+		httpClient: &http.Client{
+			Timeout: cfg.EXOLVE_TIMEOUT,
+		},
+		baseUrl:      strings.TrimRight(cfg.EXOLVE_BASE_URL, "/"),
+		apiKey:       cfg.EXOLVE_API_KEY,
+		sender:       exolvePhone(cfg.EXOLVE_SENDER),
+		maxRetries:   cfg.EXOLVE_MAX_RETRIES,
+		retryBackoff: cfg.EXOLVE_RETRY_BACKOFF,
+	}, nil
+}
+
+func (c *ExolveClient) SendSMS(ctx context.Context, phone string, text string) (string, error) {
+	// Повтор после потерянного ответа отправил бы второе SMS, поэтому
+	// отправку повторяем, только если запрос не дошел до Exolve
+	var response exolveSendSmsResponse
+	err := c.do(ctx, exolveSendSmsPath, false, exolveSendSmsRequest{
+		Number:      c.sender,
+		Destination: exolvePhone(phone),
+		Text:        text,
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+
+	if response.MessageId == "" {
+		return "", errors.Join(ErrExolveInvalidResponse, errors.New("empty message_id"))
+	}
+
+	return response.MessageId, nil
+}
+
+func (c *ExolveClient) GetStatus(ctx context.Context, messageId string) (ExolveMessageStatus, error) {
+	var response exolveGetDlrResponse
+	err := c.do(ctx, exolveGetDlrPath, true, exolveGetDlrRequest{
+		MessageId: messageId,
+	}, &response)
+	if err != nil {
+		return ExolveStatusUnknown, err
+	}
+
+	switch status := ExolveMessageStatus(strings.ToLower(response.Status)); status {
+	case ExolveStatusPending, ExolveStatusSent, ExolveStatusDelivered, ExolveStatusUndelivered:
+		return status, nil
+	default:
+		return ExolveStatusUnknown, nil
+	}
+}
+
+// idempotent == false разрешает повтор только тех попыток, которые не дошли до сервера
+func (c *ExolveClient) do(ctx context.Context, path string, idempotent bool, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Join(ErrExolveRequestFailed, err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		// Экспоненциальная задержка перед повторной попыткой
+		if attempt > 0 {
+			delay := c.retryBackoff * time.Duration(1<<(attempt-1))
+			select {
+			case <-ctx.Done():
+				return errors.Join(ErrExolveRequestFailed, ctx.Err(), lastErr)
+			case <-time.After(delay):
+			}
+		}
+
+		var retry exolveRetry
+		retry, lastErr = c.send(ctx, path, body, response)
+		if lastErr == nil || retry == exolveRetryNever || (retry == exolveRetryIdempotent && !idempotent) {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
+
+// Можно ли повторить неудачную попытку
+type exolveRetry int
+
+const (
+	exolveRetryNever exolveRetry = iota
+	// Запрос мог быть выполнен, повторяем только идемпотентные запросы
+	exolveRetryIdempotent
+	// Запрос точно не выполнен
+	exolveRetryAlways
+)
+
+func (c *ExolveClient) send(ctx context.Context, path string, body []byte, response interface{}) (exolveRetry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl+path, bytes.NewReader(body))
+	if err != nil {
+		return exolveRetryNever, errors.Join(ErrExolveRequestFailed, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Отмену контекста не повторяем. Ошибка соединения значит, что запрос
+		// не отправлен, а после таймаута он мог успеть выполниться
+		switch {
+		case ctx.Err() != nil:
+			return exolveRetryNever, errors.Join(ErrExolveRequestFailed, err)
+		case isDialError(err):
+			return exolveRetryAlways, errors.Join(ErrExolveRequestFailed, err)
+		default:
+			return exolveRetryIdempotent, errors.Join(ErrExolveRequestFailed, err)
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, exolveMaxResponseBytes))
+	if err != nil {
+		return exolveRetryIdempotent, errors.Join(ErrExolveRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return exolveStatusError(resp.StatusCode, respBody)
+	}
+
+	if err := json.Unmarshal(respBody, response); err != nil {
+		return exolveRetryNever, errors.Join(ErrExolveInvalidResponse, err)
+	}
+
+	return exolveRetryNever, nil
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func exolveStatusError(statusCode int, body []byte) (exolveRetry, error) {
+	message := strings.TrimSpace(string(body))
+	var errorResponse exolveErrorResponse
+	if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
+		message = errorResponse.Error.Message
+	}
+	detail := fmt.Errorf("status %d: %s", statusCode, message)
+
+	switch {
+	// Превышение лимита отклоняет запрос до выполнения, а 5xx может прийти после отправки
+	case statusCode == http.StatusTooManyRequests:
+		return exolveRetryAlways, errors.Join(ErrExolveRateLimited, detail)
+	case statusCode >= http.StatusInternalServerError:
+		return exolveRetryIdempotent, errors.Join(ErrExolveUnavailable, detail)
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return exolveRetryNever, errors.Join(ErrExolveUnauthorized, detail)
+	default:
+		return exolveRetryNever, errors.Join(ErrExolveBadRequest, detail)
+	}
+}
+
+// Exolve принимает номера без "+": 79991234567
+func exolvePhone(phone string) string {
+	return strings.TrimPrefix(phone, "+")
+}
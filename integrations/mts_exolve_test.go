@@ -0,0 +1,192 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+)
+
+func newTestExolveClient(t *testing.T, baseUrl string, timeout time.Duration) *ExolveClient {
+	t.Helper()
+	client, err := NewExolveClient(config.Exolve{
+		EXOLVE_BASE_URL:      baseUrl,
+		EXOLVE_API_KEY:       "test-key",
+		EXOLVE_SENDER:        "+79990000000",
+		EXOLVE_TIMEOUT:       timeout,
+		EXOLVE_MAX_RETRIES:   2,
+		EXOLVE_RETRY_BACKOFF: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewExolveClient: %v", err)
+	}
+	return client
+}
+
+func TestExolveSendSMS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != exolveSendSmsPath {
+			t.Errorf("path = %q, want %q", r.URL.Path, exolveSendSmsPath)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var request exolveSendSmsRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		want := exolveSendSmsRequest{Number: "79990000000", Destination: "79991234567", Text: "hello"}
+		if request != want {
+			t.Errorf("request = %+v, want %+v", request, want)
+		}
+		w.Write([]byte(`{"message_id":"42"}`))
+	}))
+	defer server.Close()
+
+	messageId, err := newTestExolveClient(t, server.URL, time.Second).SendSMS(context.Background(), "+79991234567", "hello")
+	if err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if messageId != "42" {
+		t.Errorf("message id = %q, want %q", messageId, "42")
+	}
+}
+
+func TestExolveErrorMapping(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrExolveBadRequest},
+		{http.StatusUnauthorized, ErrExolveUnauthorized},
+		{http.StatusForbidden, ErrExolveUnauthorized},
+		{http.StatusTooManyRequests, ErrExolveRateLimited},
+		{http.StatusServiceUnavailable, ErrExolveUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"error":{"code":1,"message":"provider says no"}}`))
+			}))
+			defer server.Close()
+
+			_, err := newTestExolveClient(t, server.URL, time.Second).SendSMS(context.Background(), "+79991234567", "hello")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExolveRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		send      bool
+		wantCalls int32
+	}{
+		// Статус можно запрашивать повторно, отправку после 5xx нет
+		{"GetStatus after 5xx", http.StatusBadGateway, false, 3},
+		{"SendSMS after 5xx", http.StatusBadGateway, true, 1},
+		{"SendSMS after rate limit", http.StatusTooManyRequests, true, 3},
+		{"GetStatus after bad request", http.StatusBadRequest, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := newTestExolveClient(t, server.URL, time.Second)
+			var err error
+			if tt.send {
+				_, err = client.SendSMS(context.Background(), "+79991234567", "hello")
+			} else {
+				_, err = client.GetStatus(context.Background(), "42")
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExolveRetryRecovers(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"status":"DELIVERED"}`))
+	}))
+	defer server.Close()
+
+	status, err := newTestExolveClient(t, server.URL, time.Second).GetStatus(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if status != ExolveStatusDelivered {
+		t.Errorf("status = %q, want %q", status, ExolveStatusDelivered)
+	}
+}
+
+func TestExolveTimeout(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// После таймаута SMS могло уйти, повторять отправку нельзя
+	_, err := newTestExolveClient(t, server.URL, 50*time.Millisecond).SendSMS(context.Background(), "+79991234567", "hello")
+	if !errors.Is(err, ErrExolveRequestFailed) {
+		t.Fatalf("error = %v, want %v", err, ErrExolveRequestFailed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestExolveDialErrorRetried(t *testing.T) {
+	// Порт без слушателя: соединение не устанавливается, запрос точно не отправлен
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := newTestExolveClient(t, "http://"+addr, time.Second)
+	_, err = client.SendSMS(context.Background(), "+79991234567", "hello")
+	if !errors.Is(err, ErrExolveRequestFailed) {
+		t.Fatalf("error = %v, want %v", err, ErrExolveRequestFailed)
+	}
+	if retry, _ := client.send(context.Background(), exolveSendSmsPath, nil, nil); retry != exolveRetryAlways {
+		t.Errorf("retry = %v, want %v", retry, exolveRetryAlways)
+	}
+}
+
+func TestExolveNegativeRetries(t *testing.T) {
+	_, err := NewExolveClient(config.Exolve{EXOLVE_MAX_RETRIES: -1})
+	if !errors.Is(err, ErrExolveInvalidConfig) {
+		t.Fatalf("error = %v, want %v", err, ErrExolveInvalidConfig)
+	}
+}
@@ -1 +1,8 @@
 package interfaces
+
+import "context"
+
+type MessageService interface {
+	SendOTP(ctx context.Context, phone string, code string) error
+	SendNotification(ctx context.Context, phone string, text string) error
+}
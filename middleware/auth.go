@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Grubiha/auth_session/domain"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+type SessionFinder interface {
+	FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error)
+}
+
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type AuthMiddleware struct {
+	sessions     SessionFinder
+	cookieName   string
+	errorHandler ErrorHandler
+}
+
+type sessionKey struct{}
+
+type session struct {
+	id   string
+	info domain.SessionInfo
+}
+
+// errorHandler == nil означает ответы через WriteError
+func NewAuthMiddleware(sessions SessionFinder, cookieName string, errorHandler ErrorHandler) *AuthMiddleware {
+	if errorHandler == nil {
+		errorHandler = WriteError
+	}
+	return &AuthMiddleware{
+		sessions:     sessions,
+		cookieName:   cookieName,
+		errorHandler: errorHandler,
+	}
+}
+
+// Берет токен сессии из Authorization: Bearer или из cookie и кладет
+// domain.SessionInfo в контекст запроса. Вместо репозитория сессий можно
+// передать accesstoken.Verifier для проверки подписанных access-токенов
+func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := m.token(r)
+		if token == "" {
+			m.errorHandler(w, r, ErrUnauthorized)
+			return
+		}
+
+		info, err := m.sessions.FindSessionInfo(r.Context(), domain.FindSessionByTokenDto{Token: token})
+		if err != nil {
+			if isUnauthenticated(err) {
+				err = errors.Join(ErrUnauthorized, err)
+			}
+			m.errorHandler(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithSession(r.Context(), info.SessionId, info)))
+	})
+}
+
+// Пропускает запрос, только если уровень роли сессии не ниже role.
+// Должен стоять после Authenticate. Роли загружаются из базы, поэтому
+// неизвестная role проверяется при запросе и закрывает доступ
+func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := SessionFromContext(r.Context()); !ok {
+				m.errorHandler(w, r, ErrUnauthorized)
+				return
+			}
+			if err := domain.ValidateUserRole(role); err != nil {
+				slog.Error("route requires unknown role", "role", role, "error", err)
+				m.errorHandler(w, r, ErrForbidden)
+				return
+			}
+			if !HasRole(r.Context(), role) {
+				m.errorHandler(w, r, ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (m *AuthMiddleware) token(r *http.Request) string {
+	if token, ok := BearerToken(r.Header.Get("Authorization")); ok {
+		return token
+	}
+	if m.cookieName != "" {
+		if cookie, err := r.Cookie(m.cookieName); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// Достает токен из значения Authorization. Схема сравнивается без учета
+// регистра (RFC 7235)
+func BearerToken(value string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func ContextWithSession(ctx context.Context, sessionId string, info domain.SessionInfo) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session{
+		id:   sessionId,
+		info: info,
+	})
+}
+
+func SessionFromContext(ctx context.Context) (domain.SessionInfo, bool) {
+	s, ok := ctx.Value(sessionKey{}).(session)
+	return s.info, ok
+}
+
+func SessionIdFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(sessionKey{}).(session)
+	return s.id, ok
+}
+
+func HasRole(ctx context.Context, role string) bool {
+	info, ok := SessionFromContext(ctx)
+	return ok && domain.RoleCovers(info.UserRole, role)
+}
+
+// Ошибки, после которых клиенту нужно войти заново, а не повторить запрос
+func isUnauthenticated(err error) bool {
+	return errors.Is(err, domain.ErrValidationError) ||
+		errors.Is(err, domain.ErrInvalidToken) ||
+		errors.Is(err, domain.ErrSessionNotFound)
+}
+
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := StatusCode(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: http.StatusText(status)})
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS sessions_retired_refresh_tokens;
+DROP INDEX IF EXISTS sessions_family_id_idx;
+ALTER TABLE sessions DROP COLUMN IF EXISTS generation;
+ALTER TABLE sessions DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,14 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS family_id uuid;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS generation integer NOT NULL DEFAULT 0;
+UPDATE sessions SET family_id = session_id WHERE family_id IS NULL;
+ALTER TABLE sessions ALTER COLUMN family_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS sessions_family_id_idx ON sessions (family_id);
+
+CREATE TABLE IF NOT EXISTS sessions_retired_refresh_tokens (
+  refresh_token_hash bytea PRIMARY KEY,
+  session_id uuid NOT NULL,
+  family_id uuid NOT NULL,
+  generation integer NOT NULL,
+
+  FOREIGN KEY (session_id) REFERENCES sessions(session_id) ON DELETE CASCADE
+);
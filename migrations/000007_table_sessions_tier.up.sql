@@ -0,0 +1,2 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS session_tier text NOT NULL DEFAULT 'normal'
+  CHECK (session_tier IN ('short', 'normal', 'long'));
@@ -0,0 +1,9 @@
+-- Задания может не быть: его ставит сервис и только в режиме pg_cron
+DO $$
+BEGIN
+  IF to_regclass('cron.job') IS NOT NULL THEN
+    PERFORM cron.unschedule(jobid) FROM cron.job WHERE jobname = 'sessions_cleanup';
+  END IF;
+END;
+$$;
+DROP FUNCTION IF EXISTS cleanup_expired_sessions(integer, text);
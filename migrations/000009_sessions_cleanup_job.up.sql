@@ -0,0 +1,29 @@
+-- Задание pg_cron ставит сервис (SessionRepository.ScheduleCleanup) по
+-- SESSION_CLEANUP_MODE, SESSION_CLEANUP_INTERVAL и SESSION_CLEANUP_BATCH_SIZE.
+-- refresh_expires_at хранит местное время сервиса, поэтому сравнение идет
+-- с now() в его часовом поясе, а не с LOCALTIMESTAMP базы
+CREATE OR REPLACE FUNCTION cleanup_expired_sessions(batch_size integer, time_zone text) RETURNS integer AS $$
+DECLARE
+  deleted integer := 0;
+  batch integer;
+BEGIN
+  IF batch_size IS NULL OR batch_size < 1 THEN
+    RAISE EXCEPTION 'cleanup_expired_sessions: batch_size must be at least 1, got %', batch_size;
+  END IF;
+
+  LOOP
+    DELETE FROM sessions WHERE session_id IN (
+      SELECT session_id FROM sessions
+      WHERE refresh_expires_at < (now() AT TIME ZONE time_zone)
+      ORDER BY refresh_expires_at
+      LIMIT batch_size
+    );
+    GET DIAGNOSTICS batch = ROW_COUNT;
+    deleted := deleted + batch;
+    EXIT WHEN batch < batch_size;
+  END LOOP;
+
+  RAISE LOG 'cleanup_expired_sessions: deleted % sessions', deleted;
+  RETURN deleted;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,10 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS token_hash bytea;
+-- Токеном существующих сессий был session_id, который мог попасть в логи и
+-- ответы API, поэтому они получают случайный хеш, которому не соответствует
+-- ни один токен. Такая сессия продолжает работать через refresh-токен,
+-- который выдаст новый токен сессии, либо пользователь входит заново
+UPDATE sessions
+SET token_hash = sha256(uuid_send(uuid_generate_v4()) || uuid_send(uuid_generate_v4()))
+WHERE token_hash IS NULL;
+ALTER TABLE sessions ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE sessions ADD CONSTRAINT sessions_token_hash_key UNIQUE (token_hash);
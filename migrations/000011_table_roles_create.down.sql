@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_session_role_fkey;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_user_role_fkey;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS roles (
+  role_name varchar(50) PRIMARY KEY CHECK (role_name ~ '^[a-z][a-z0-9_]*$'),
+  role_level integer NOT NULL
+);
+
+INSERT INTO roles (role_name, role_level) VALUES ('user', 0), ('manager', 1), ('admin', 2)
+ON CONFLICT (role_name) DO NOTHING;
+
+INSERT INTO roles (role_name, role_level) SELECT DISTINCT user_role, 0 FROM users
+ON CONFLICT (role_name) DO NOTHING;
+INSERT INTO roles (role_name, role_level) SELECT DISTINCT session_role, 0 FROM sessions
+ON CONFLICT (role_name) DO NOTHING;
+
+ALTER TABLE users ADD CONSTRAINT users_user_role_fkey
+  FOREIGN KEY (user_role) REFERENCES roles(role_name) ON UPDATE CASCADE;
+ALTER TABLE sessions ADD CONSTRAINT sessions_session_role_fkey
+  FOREIGN KEY (session_role) REFERENCES roles(role_name) ON UPDATE CASCADE;
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// SQL-миграции встраиваются в бинарник, чтобы мигратор не зависел от рабочего каталога
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,8 +1,14 @@
 package repos
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/Grubiha/auth_session/domain"
+)
 
 var (
+	ErrInvalidSessionConfig = errors.New("invalid session config")
+
 	ErrPostgresQueryFailed = errors.New("postgres query failed")
 	ErrRedisQueryFailed    = errors.New("redis query failed")
 	ErrRoleMistmatch       = errors.New("role mismatch")
@@ -10,5 +16,14 @@ var (
 	ErrUniqueViolation = errors.New("unique violation")
 
 	ErrUserNotFound    = errors.New("user not found")
-	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotFound = domain.ErrSessionNotFound
+	ErrSessionExpired  = errors.New("session expired")
+
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	ErrOtpNotFound         = errors.New("otp not found")
+	ErrOtpMismatch         = errors.New("otp mismatch")
+	ErrOtpAttemptsExceeded = errors.New("otp attempts exceeded")
+	ErrOtpResendCooldown   = errors.New("otp resend cooldown")
+	ErrInvalidOtpConfig    = errors.New("invalid otp config")
 )
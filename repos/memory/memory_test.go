@@ -0,0 +1,11 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/Grubiha/auth_session/repos/repotest"
+)
+
+func TestRepositories(t *testing.T) {
+	repotest.Run(t, repotest.MemoryFactory)
+}
@@ -0,0 +1,420 @@
+package memory
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/google/uuid"
+)
+
+type SessionRepository struct {
+	store *Store
+}
+
+var _ domain.SessionRepository = (*SessionRepository)(nil)
+
+func NewSessionRepository(store *Store) *SessionRepository {
+	return &SessionRepository{
+		store: store,
+	}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, dto domain.CreateSessionDto) (domain.SessionTokens, error) {
+	return r.create(dto, 0)
+}
+
+func (r *SessionRepository) CreateWithLimit(ctx context.Context, dto domain.CreateSessionDto) (domain.SessionTokens, error) {
+	return r.create(dto, r.store.cfg.SESSION_MAX_USER_SESSIONS)
+}
+
+// limit <= 0 отключает ограничение количества сессий
+func (r *SessionRepository) create(dto domain.CreateSessionDto, limit int) (domain.SessionTokens, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[dto.UserId]
+	if !ok {
+		return domain.SessionTokens{}, repos.ErrUserNotFound
+	}
+
+	// Проверяем можно ли выдать запрошенную роль
+	if !domain.RoleCovers(u.role, dto.SessionRole) {
+		return domain.SessionTokens{}, repos.ErrRoleMistmatch
+	}
+
+	// Вытесняем самые старые по времени создания действующие сессии,
+	// оставляя место под новую. Истекшие в лимит не входят, как и в
+	// GetUserSessionCount, их удаляет CleanupExpired
+	now := r.store.now()
+	if limit > 0 {
+		sessions := r.liveRoleSessions(dto.UserId, dto.SessionRole, now)
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].createdAt.After(sessions[j].createdAt)
+		})
+		for i := limit - 1; i < len(sessions); i++ {
+			r.store.deleteSession(sessions[i])
+		}
+	}
+
+	sessionToken, tokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	refreshToken, refreshTokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	// Новая сессия открывает собственное семейство токенов
+	ttl, refreshTtl := sessionTierTtl(r.store.cfg, dto.Tier)
+	sess := &session{
+		id:               uuid.NewString(),
+		tokenHash:        tokenHash,
+		refreshTokenHash: refreshTokenHash,
+		userId:           dto.UserId,
+		sessionRole:      dto.SessionRole,
+		tier:             dto.Tier,
+		expiresAt:        now.Add(ttl),
+		refreshExpiresAt: now.Add(refreshTtl),
+		createdAt:        now,
+		lastSeenAt:       now,
+		ipAddress:        normalizeIpAddress(dto.IpAddress),
+		userAgent:        dto.UserAgent,
+		deviceLabel:      domain.ParseUserAgent(dto.UserAgent),
+		deviceName:       dto.DeviceName,
+	}
+	sess.familyId = sess.id
+	sess.retiredRefreshTokenHashes = make(map[string]int)
+	r.store.sessions[sess.id] = sess
+	r.store.byTokenHash[tokenHash] = sess.id
+	r.store.byRefreshTokenHash[refreshTokenHash] = sess.id
+
+	return domain.SessionTokens{
+		SessionInfo:  r.sessionInfo(sess, u),
+		SessionToken: sessionToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (r *SessionRepository) Refresh(ctx context.Context, dto domain.RefreshSessionDto) (domain.SessionTokens, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	oldRefreshTokenHash := hashToken(dto.RefreshToken)
+	sessionId, ok := r.store.byRefreshTokenHash[oldRefreshTokenHash]
+	if !ok {
+		return domain.SessionTokens{}, r.revokeReusedFamily(oldRefreshTokenHash)
+	}
+	sess := r.store.sessions[sessionId]
+	u := r.store.users[sess.userId]
+
+	// Проверяем окно обновления
+	now := r.store.now()
+	if !sess.refreshExpiresAt.After(now) {
+		return domain.SessionTokens{}, repos.ErrSessionExpired
+	}
+
+	// Роль пользователя могла быть понижена после входа
+	if !domain.RoleCovers(u.role, sess.sessionRole) {
+		return domain.SessionTokens{}, repos.ErrRoleMistmatch
+	}
+
+	// Ротируем оба токена и продлеваем сессию на срок ее уровня,
+	// но не дальше окна обновления
+	sessionToken, tokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	refreshToken, refreshTokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	ttl, _ := sessionTierTtl(r.store.cfg, sess.tier)
+	expiresAt := now.Add(ttl)
+	if expiresAt.After(sess.refreshExpiresAt) {
+		expiresAt = sess.refreshExpiresAt
+	}
+
+	r.store.deleteSession(sess)
+	sess.retiredRefreshTokenHashes[oldRefreshTokenHash] = sess.generation
+	sess.tokenHash = tokenHash
+	sess.refreshTokenHash = refreshTokenHash
+	sess.expiresAt = expiresAt
+	sess.lastSeenAt = now
+	sess.generation++
+	r.store.sessions[sess.id] = sess
+	r.store.byTokenHash[tokenHash] = sess.id
+	r.store.byRefreshTokenHash[refreshTokenHash] = sess.id
+
+	return domain.SessionTokens{
+		SessionInfo:  r.sessionInfo(sess, u),
+		SessionToken: sessionToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Предъявление устаревшего поколения refresh-токена считается кражей:
+// отзываем все семейство сессий. Вызывается под store.mu
+func (r *SessionRepository) revokeReusedFamily(refreshTokenHash string) error {
+	var familyId string
+	for _, sess := range r.store.sessions {
+		// Токен считается устаревшим, только если сессия уже ушла на следующее поколение
+		if generation, ok := sess.retiredRefreshTokenHashes[refreshTokenHash]; ok && generation < sess.generation {
+			familyId = sess.familyId
+		}
+	}
+	if familyId == "" {
+		return repos.ErrSessionNotFound
+	}
+
+	for _, sess := range r.store.sessions {
+		if sess.familyId == familyId {
+			r.store.deleteSession(sess)
+		}
+	}
+
+	return repos.ErrRefreshTokenReused
+}
+
+func (r *SessionRepository) GetUserSessionCount(ctx context.Context, dto domain.FindSessionWithRoleDto) (int, error) {
+	if err := dto.Validate(); err != nil {
+		return 0, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return len(r.liveRoleSessions(dto.Id, dto.SessionRole, r.store.now())), nil
+}
+
+func (r *SessionRepository) DeleteOldestUserSession(ctx context.Context, dto domain.FindSessionWithRoleDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var oldest *session
+	for _, sess := range r.liveRoleSessions(dto.Id, dto.SessionRole, r.store.now()) {
+		if oldest == nil || sess.createdAt.Before(oldest.createdAt) {
+			oldest = sess
+		}
+	}
+	if oldest != nil {
+		r.store.deleteSession(oldest)
+	}
+	return nil
+}
+
+func (r *SessionRepository) Delete(ctx context.Context, dto domain.FindSessionDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if sess, ok := r.store.sessions[dto.Id]; ok {
+		r.store.deleteSession(sess)
+	}
+	return nil
+}
+
+func (r *SessionRepository) FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.SessionInfo{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sessionId, ok := r.store.byTokenHash[hashToken(dto.Token)]
+	if !ok {
+		return domain.SessionInfo{}, repos.ErrSessionNotFound
+	}
+	sess := r.store.sessions[sessionId]
+
+	// Истекшая сессия ведет себя как пропавший по TTL ключ Redis
+	now := r.store.now()
+	if !sess.expiresAt.After(now) {
+		return domain.SessionInfo{}, repos.ErrSessionNotFound
+	}
+
+	// В скользящем режиме каждое обращение продлевает сессию в пределах окна обновления
+	if r.store.cfg.SESSION_SLIDING {
+		ttl, _ := sessionTierTtl(r.store.cfg, sess.tier)
+		expiresAt := now.Add(ttl)
+		if expiresAt.After(sess.refreshExpiresAt) {
+			expiresAt = sess.refreshExpiresAt
+		}
+		sess.expiresAt = expiresAt
+		sess.lastSeenAt = now
+	}
+
+	return r.sessionInfo(sess, r.store.users[sess.userId]), nil
+}
+
+func (r *SessionRepository) ListUserSessions(ctx context.Context, dto domain.FindUserDto) ([]domain.Session, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	var sessions []domain.Session
+	for _, sess := range r.store.userSessions(dto.Id) {
+		if !sess.refreshExpiresAt.After(now) {
+			continue
+		}
+		sessions = append(sessions, domain.Session{
+			SessionInfo:      r.sessionInfo(sess, r.store.users[sess.userId]),
+			Tier:             sess.tier,
+			ExpiresAt:        sess.expiresAt,
+			RefreshExpiresAt: sess.refreshExpiresAt,
+			CreatedAt:        sess.createdAt,
+			LastSeenAt:       sess.lastSeenAt,
+			IpAddress:        sess.ipAddress,
+			UserAgent:        sess.userAgent,
+			DeviceLabel:      sess.deviceLabel,
+			DeviceName:       sess.deviceName,
+		})
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].RefreshExpiresAt.After(sessions[j].RefreshExpiresAt)
+	})
+
+	return sessions, nil
+}
+
+func (r *SessionRepository) RevokeUserSession(ctx context.Context, dto domain.FindUserSessionDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sess, ok := r.store.sessions[dto.Id]
+	if !ok || sess.userId != dto.UserId {
+		return repos.ErrSessionNotFound
+	}
+	r.store.deleteSession(sess)
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllUserSessions(ctx context.Context, dto domain.FindUserDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, sess := range r.store.userSessions(dto.Id) {
+		r.store.deleteSession(sess)
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeOtherUserSessions(ctx context.Context, dto domain.FindUserSessionDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, sess := range r.store.userSessions(dto.UserId) {
+		if sess.id != dto.Id {
+			r.store.deleteSession(sess)
+		}
+	}
+	return nil
+}
+
+// Удаляет сессии с истекшим окном обновления, аналог repos.SessionRepository.CleanupExpired
+func (r *SessionRepository) CleanupExpired(ctx context.Context) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	deleted := 0
+	for _, sess := range r.store.sessions {
+		if sess.refreshExpiresAt.Before(now) {
+			r.store.deleteSession(sess)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Вызывается под store.mu
+func (r *SessionRepository) roleSessions(userId, sessionRole string) []*session {
+	var sessions []*session
+	for _, sess := range r.store.userSessions(userId) {
+		if sess.sessionRole == sessionRole {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+// Сессии роли с открытым окном обновления
+func (r *SessionRepository) liveRoleSessions(userId, sessionRole string, now time.Time) []*session {
+	var sessions []*session
+	for _, sess := range r.roleSessions(userId, sessionRole) {
+		if sess.refreshExpiresAt.After(now) {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+// Имя берется у пользователя, роль у сессии, как в информации о сессии в Redis
+func (r *SessionRepository) sessionInfo(sess *session, u *user) domain.SessionInfo {
+	return domain.SessionInfo{
+		SessionId: sess.id,
+		UserId:    sess.userId,
+		UserName:  u.name,
+		UserRole:  sess.sessionRole,
+	}
+}
+
+// Приводит адрес к виду, который возвращает host(inet) в PostgreSQL
+func normalizeIpAddress(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.String()
+}
+
+func sessionTierTtl(cfg config.Session, tier domain.SessionTier) (time.Duration, time.Duration) {
+	switch tier {
+	case domain.SessionTierShort:
+		return cfg.SESSION_EXP_SHORT, cfg.SESSION_REFRESH_EXP_SHORT
+	case domain.SessionTierLong:
+		return cfg.SESSION_EXP_LONG, cfg.SESSION_REFRESH_EXPLONG
+	default:
+		return cfg.SESSION_EXP, cfg.SESSION_REFRESH_EXP
+	}
+}
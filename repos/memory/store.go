@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+)
+
+const secretTokenBytes = 32
+
+// Общее хранилище пользователей и сессий: как и в PostgreSQL, удаление
+// пользователя каскадно удаляет его сессии, поэтому оба репозитория
+// работают с одним Store под одной блокировкой
+type Store struct {
+	mu  sync.Mutex
+	cfg config.Session
+	now func() time.Time
+
+	users    map[string]*user
+	sessions map[string]*session
+
+	// Индексы по хешам токенов, аналог ключей Redis и уникальных колонок
+	byTokenHash        map[string]string
+	byRefreshTokenHash map[string]string
+}
+
+type user struct {
+	id    string
+	name  string
+	phone string
+	role  string
+}
+
+type session struct {
+	id         string
+	familyId   string
+	generation int
+
+	tokenHash        string
+	refreshTokenHash string
+
+	userId      string
+	sessionRole string
+	tier        domain.SessionTier
+
+	expiresAt        time.Time
+	refreshExpiresAt time.Time
+	createdAt        time.Time
+	lastSeenAt       time.Time
+
+	ipAddress   string
+	userAgent   string
+	deviceLabel string
+	deviceName  string
+
+	// Поколения по хешам прежних refresh-токенов, удаляются вместе с сессией
+	retiredRefreshTokenHashes map[string]int
+}
+
+// now == nil означает time.Now; в тестах передают управляемые часы,
+// чтобы проверять истечение сессий без ожидания
+func NewStore(cfg config.Session, now func() time.Time) *Store {
+	if now == nil {
+		now = time.Now
+	}
+	return &Store{
+		cfg:                cfg,
+		now:                now,
+		users:              make(map[string]*user),
+		sessions:           make(map[string]*session),
+		byTokenHash:        make(map[string]string),
+		byRefreshTokenHash: make(map[string]string),
+	}
+}
+
+// Вызывается под s.mu
+func (s *Store) deleteSession(sess *session) {
+	delete(s.sessions, sess.id)
+	delete(s.byTokenHash, sess.tokenHash)
+	delete(s.byRefreshTokenHash, sess.refreshTokenHash)
+}
+
+// Вызывается под s.mu
+func (s *Store) userSessions(userId string) []*session {
+	var sessions []*session
+	for _, sess := range s.sessions {
+		if sess.userId == userId {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+func newSecretToken() (string, string, error) {
+	buf := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/google/uuid"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) domain.UserRepository {
+	return &UserRepository{
+		store: store,
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, dto domain.CreateUserDto) (string, error) {
+	if err := dto.Validate(); err != nil {
+		return "", err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.phoneTaken(dto.Phone, "") {
+		return "", repos.ErrUniqueViolation
+	}
+
+	// Роль по умолчанию, как DEFAULT в таблице users
+	role := domain.UserRoleUser
+	if dto.Role != nil {
+		role = *dto.Role
+	}
+
+	id := uuid.NewString()
+	r.store.users[id] = &user{
+		id:    id,
+		name:  dto.Name,
+		phone: dto.Phone,
+		role:  role,
+	}
+
+	return id, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, dto domain.FindUserDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[dto.Id]; !ok {
+		return repos.ErrUserNotFound
+	}
+
+	// Сессии удаляются каскадно
+	for _, sess := range r.store.userSessions(dto.Id) {
+		r.store.deleteSession(sess)
+	}
+	delete(r.store.users, dto.Id)
+
+	return nil
+}
+
+func (r *UserRepository) Find(ctx context.Context, dto domain.FindUserDto) (domain.User, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.User{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[dto.Id]
+	if !ok {
+		return domain.User{}, repos.ErrUserNotFound
+	}
+
+	return u.entity(), nil
+}
+
+func (r *UserRepository) FindByPhone(ctx context.Context, dto domain.FindUserByPhoneDto) (domain.User, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.User{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.phone == dto.Phone {
+			return u.entity(), nil
+		}
+	}
+
+	return domain.User{}, repos.ErrUserNotFound
+}
+
+func (r *UserRepository) Update(ctx context.Context, dto domain.UpdateUserDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[dto.Id]
+	if !ok {
+		return repos.ErrUserNotFound
+	}
+
+	if dto.Phone != nil && r.phoneTaken(*dto.Phone, dto.Id) {
+		return repos.ErrUniqueViolation
+	}
+
+	if dto.Name != nil {
+		u.name = *dto.Name
+	}
+	if dto.Phone != nil {
+		u.phone = *dto.Phone
+	}
+
+	// Сессии с ролью выше новой роли пользователя отзываем.
+	// Имя в информации о сессии берется из пользователя, переписывать его не нужно
+	if dto.Role != nil {
+		u.role = *dto.Role
+		for _, sess := range r.store.userSessions(dto.Id) {
+			if !domain.RoleCovers(u.role, sess.sessionRole) {
+				r.store.deleteSession(sess)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Вызывается под store.mu; exceptId исключает самого обновляемого пользователя
+func (r *UserRepository) phoneTaken(phone string, exceptId string) bool {
+	for _, u := range r.store.users {
+		if u.phone == phone && u.id != exceptId {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *user) entity() domain.User {
+	return domain.User{
+		Id:    u.id,
+		Name:  u.name,
+		Phone: u.phone,
+		Role:  u.role,
+	}
+}
@@ -0,0 +1,161 @@
+package repos
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const otpMinSecretBytes = 32
+
+// Результаты скрипта выпуска кода
+const (
+	otpCreated = iota
+	otpCreateCooldown
+	otpCreateAttemptsExceeded
+)
+
+// Результаты скрипта проверки кода
+const (
+	otpResultNotFound = iota
+	otpResultMatch
+	otpResultMismatch
+	otpResultAttemptsExceeded
+)
+
+// Новый код не сбрасывает счетчик попыток номера, иначе перебор
+// продолжался бы повторными запросами кода. Пока идет пауза между
+// отправками или номер заблокирован, код не выпускается
+var createOtpScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[2]) or "0") >= tonumber(ARGV[4]) then
+	return 2
+end
+if tonumber(ARGV[3]) > 0 and not redis.call("SET", KEYS[3], "1", "PX", ARGV[3], "NX") then
+	return 1
+end
+redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], "code_hash", ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 0
+`)
+
+// Счетчик попыток увеличивается до сравнения, поэтому перебор
+// ограничен maxAttempts даже при параллельных запросах
+var verifyOtpScript = redis.NewScript(`
+local hash = redis.call("HGET", KEYS[1], "code_hash")
+if not hash then
+	return 0
+end
+local attempts = redis.call("INCR", KEYS[2])
+if attempts == 1 then
+	redis.call("PEXPIRE", KEYS[2], ARGV[3])
+end
+if attempts > tonumber(ARGV[2]) then
+	redis.call("DEL", KEYS[1])
+	return 3
+end
+if hash == ARGV[1] then
+	redis.call("DEL", KEYS[1], KEYS[2])
+	return 1
+end
+return 2
+`)
+
+type OtpRepository struct {
+	redisClient redis.UniversalClient
+	cfg         config.Otp
+}
+
+func NewOtpRepository(redisClient redis.UniversalClient, cfg config.Otp) (*OtpRepository, error) {
+	switch {
+	case len(cfg.OTP_SECRET) < otpMinSecretBytes:
+		return nil, fmt.Errorf("%w: OTP_SECRET must be at least %d bytes", ErrInvalidOtpConfig, otpMinSecretBytes)
+	case cfg.OTP_EXP <= 0:
+		return nil, fmt.Errorf("%w: OTP_EXP must be positive", ErrInvalidOtpConfig)
+	case cfg.OTP_MAX_ATTEMPTS < 1:
+		return nil, fmt.Errorf("%w: OTP_MAX_ATTEMPTS must be at least 1", ErrInvalidOtpConfig)
+	case cfg.OTP_ATTEMPTS_WINDOW < cfg.OTP_EXP:
+		return nil, fmt.Errorf("%w: OTP_ATTEMPTS_WINDOW must not be shorter than OTP_EXP", ErrInvalidOtpConfig)
+	case cfg.OTP_RESEND_COOLDOWN < 0:
+		return nil, fmt.Errorf("%w: OTP_RESEND_COOLDOWN must not be negative", ErrInvalidOtpConfig)
+	}
+	return &OtpRepository{
+		redisClient: redisClient,
+		cfg:         cfg,
+	}, nil
+}
+
+func (r *OtpRepository) Create(ctx context.Context, dto domain.CreateOtpDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	result, err := createOtpScript.Run(ctx, r.redisClient,
+		otpKeys(dto.Phone),
+		r.hashOtp(dto.Phone, dto.Code),
+		r.cfg.OTP_EXP.Milliseconds(),
+		r.cfg.OTP_RESEND_COOLDOWN.Milliseconds(),
+		r.cfg.OTP_MAX_ATTEMPTS,
+	).Int()
+	if err != nil {
+		return errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	switch result {
+	case otpCreateCooldown:
+		return ErrOtpResendCooldown
+	case otpCreateAttemptsExceeded:
+		return ErrOtpAttemptsExceeded
+	default:
+		return nil
+	}
+}
+
+func (r *OtpRepository) Verify(ctx context.Context, dto domain.VerifyOtpDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	result, err := verifyOtpScript.Run(ctx, r.redisClient,
+		otpKeys(dto.Phone),
+		r.hashOtp(dto.Phone, dto.Code),
+		r.cfg.OTP_MAX_ATTEMPTS,
+		r.cfg.OTP_ATTEMPTS_WINDOW.Milliseconds(),
+	).Int()
+	if err != nil {
+		return errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	switch result {
+	case otpResultMatch:
+		return nil
+	case otpResultMismatch:
+		return ErrOtpMismatch
+	case otpResultAttemptsExceeded:
+		return ErrOtpAttemptsExceeded
+	default:
+		return ErrOtpNotFound
+	}
+}
+
+// Код, счетчик попыток и пауза между отправками. Общий hash tag держит
+// ключи номера в одном слоте Redis Cluster, как требуют скрипты
+func otpKeys(phone string) []string {
+	tag := "{" + phone + "}"
+	return []string{"otp:" + tag, "otp_attempts:" + tag, "otp_cooldown:" + tag}
+}
+
+// Кодов всего 10^4..10^8, поэтому без секрета хеш из дампа Redis
+// перебирается мгновенно
+func (r *OtpRepository) hashOtp(phone, code string) string {
+	mac := hmac.New(sha256.New, []byte(r.cfg.OTP_SECRET))
+	mac.Write([]byte(phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testOtpPhone = "+79990000000"
+
+func newTestOtpRepository(t *testing.T, cfg config.Otp) (*OtpRepository, *miniredis.Miniredis) {
+	t.Helper()
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	repo, err := NewOtpRepository(redisClient, cfg)
+	if err != nil {
+		t.Fatalf("NewOtpRepository: %v", err)
+	}
+	return repo, redisServer
+}
+
+func testOtpConfig() config.Otp {
+	return config.Otp{
+		OTP_LENGTH:          6,
+		OTP_EXP:             5 * time.Minute,
+		OTP_MAX_ATTEMPTS:    3,
+		OTP_ATTEMPTS_WINDOW: time.Hour,
+		OTP_RESEND_COOLDOWN: time.Minute,
+		OTP_SECRET:          strings.Repeat("s", otpMinSecretBytes),
+	}
+}
+
+func TestNewOtpRepositoryRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Otp)
+	}{
+		{"short secret", func(cfg *config.Otp) { cfg.OTP_SECRET = "short" }},
+		{"zero exp", func(cfg *config.Otp) { cfg.OTP_EXP = 0 }},
+		{"zero attempts", func(cfg *config.Otp) { cfg.OTP_MAX_ATTEMPTS = 0 }},
+		{"window shorter than exp", func(cfg *config.Otp) { cfg.OTP_ATTEMPTS_WINDOW = time.Minute }},
+		{"negative cooldown", func(cfg *config.Otp) { cfg.OTP_RESEND_COOLDOWN = -time.Second }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testOtpConfig()
+			tt.modify(&cfg)
+			if _, err := NewOtpRepository(nil, cfg); !errors.Is(err, ErrInvalidOtpConfig) {
+				t.Fatalf("got %v, want ErrInvalidOtpConfig", err)
+			}
+		})
+	}
+}
+
+func TestOtpResendCooldown(t *testing.T) {
+	ctx := context.Background()
+	repo, redisServer := newTestOtpRepository(t, testOtpConfig())
+
+	create := domain.CreateOtpDto{Phone: testOtpPhone, Code: "111111"}
+	if err := repo.Create(ctx, create); err != nil {
+		t.Fatalf("first Create: %v", err)
+	}
+	if err := repo.Create(ctx, create); !errors.Is(err, ErrOtpResendCooldown) {
+		t.Fatalf("Create inside cooldown: got %v, want ErrOtpResendCooldown", err)
+	}
+
+	redisServer.FastForward(time.Minute)
+	if err := repo.Create(ctx, create); err != nil {
+		t.Fatalf("Create after cooldown: %v", err)
+	}
+}
+
+func TestOtpAttemptsSurviveReissue(t *testing.T) {
+	ctx := context.Background()
+	cfg := testOtpConfig()
+	cfg.OTP_RESEND_COOLDOWN = 0
+	repo, _ := newTestOtpRepository(t, cfg)
+
+	// Каждая неудачная попытка идет по новому коду, но лимит общий
+	for i := 0; i < cfg.OTP_MAX_ATTEMPTS; i++ {
+		if err := repo.Create(ctx, domain.CreateOtpDto{Phone: testOtpPhone, Code: "111111"}); err != nil {
+			t.Fatalf("Create #%d: %v", i, err)
+		}
+		err := repo.Verify(ctx, domain.VerifyOtpDto{Phone: testOtpPhone, Code: "222222"})
+		if !errors.Is(err, ErrOtpMismatch) {
+			t.Fatalf("Verify #%d: got %v, want ErrOtpMismatch", i, err)
+		}
+	}
+
+	err := repo.Create(ctx, domain.CreateOtpDto{Phone: testOtpPhone, Code: "111111"})
+	if !errors.Is(err, ErrOtpAttemptsExceeded) {
+		t.Fatalf("Create after exhausted attempts: got %v, want ErrOtpAttemptsExceeded", err)
+	}
+}
+
+func TestOtpMatchResetsAttempts(t *testing.T) {
+	ctx := context.Background()
+	cfg := testOtpConfig()
+	cfg.OTP_RESEND_COOLDOWN = 0
+	repo, redisServer := newTestOtpRepository(t, cfg)
+
+	if err := repo.Create(ctx, domain.CreateOtpDto{Phone: testOtpPhone, Code: "111111"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Verify(ctx, domain.VerifyOtpDto{Phone: testOtpPhone, Code: "222222"}); !errors.Is(err, ErrOtpMismatch) {
+		t.Fatalf("Verify wrong code: got %v, want ErrOtpMismatch", err)
+	}
+	if err := repo.Verify(ctx, domain.VerifyOtpDto{Phone: testOtpPhone, Code: "111111"}); err != nil {
+		t.Fatalf("Verify right code: %v", err)
+	}
+
+	keys := otpKeys(testOtpPhone)
+	if redisServer.Exists(keys[0]) || redisServer.Exists(keys[1]) {
+		t.Fatal("code and attempts must be deleted after a match")
+	}
+}
+
+func TestHashOtpUsesSecret(t *testing.T) {
+	cfg := testOtpConfig()
+	first := &OtpRepository{cfg: cfg}
+	cfg.OTP_SECRET = strings.Repeat("t", otpMinSecretBytes)
+	second := &OtpRepository{cfg: cfg}
+
+	if first.hashOtp(testOtpPhone, "111111") == second.hashOtp(testOtpPhone, "111111") {
+		t.Fatal("hashes with different secrets must differ")
+	}
+}
@@ -0,0 +1,153 @@
+// Контрактные тесты domain.UserRepository и domain.SessionRepository.
+// Одинаковый набор проверок прогоняется против всех реализаций, чтобы их
+// поведение не расходилось. Подключается из _test.go файла:
+//
+//	func TestRepositories(t *testing.T) {
+//		repotest.Run(t, repotest.MemoryFactory)
+//	}
+//
+// Для PostgreSQL и Redis нужна база с примененными миграциями и Redis
+// (например miniredis). Фабрика очищает таблицы и Redis перед каждым тестом,
+// поэтому рабочую базу подключать нельзя. В repos/repos_test.go набор
+// прогоняется на временном PostgreSQL с тегом postgres (make test-postgres)
+package repotest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/Grubiha/auth_session/repos/memory"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type Repositories struct {
+	Users    domain.UserRepository
+	Sessions domain.SessionRepository
+
+	// Сдвигает часы репозиториев вперед. nil означает реальное время,
+	// тогда проверки истечения сессий пропускаются
+	Advance func(d time.Duration)
+}
+
+// Возвращает пустые репозитории, настроенные по cfg
+type Factory func(t *testing.T, cfg config.Session) Repositories
+
+// Уровни выбраны так, чтобы окна обновления не пересекались и порядок
+// ListUserSessions не зависел от точности часов хранилища
+func sessionConfig() config.Session {
+	return config.Session{
+		SESSION_MAX_USER_SESSIONS: 2,
+
+		SESSION_EXP_SHORT:         10 * time.Minute,
+		SESSION_REFRESH_EXP_SHORT: time.Hour,
+
+		SESSION_EXP:         time.Hour,
+		SESSION_REFRESH_EXP: 24 * time.Hour,
+
+		SESSION_EXP_LONG:        24 * time.Hour,
+		SESSION_REFRESH_EXPLONG: 30 * 24 * time.Hour,
+
+		SESSION_RECONCILE_POSTGRES_ORPHANS: repos.ReconcilePostgresOrphansRestore,
+		SESSION_CLEANUP_MODE:               repos.SessionCleanupDisabled,
+	}
+}
+
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) {
+		RunUsers(t, newRepos)
+	})
+	t.Run("Sessions", func(t *testing.T) {
+		RunSessions(t, newRepos)
+	})
+}
+
+func MemoryFactory(t *testing.T, cfg config.Session) Repositories {
+	clock := &manualClock{now: time.Now()}
+	store := memory.NewStore(cfg, clock.Now)
+	return Repositories{
+		Users:    memory.NewUserRepository(store),
+		Sessions: memory.NewSessionRepository(store),
+		Advance:  clock.Advance,
+	}
+}
+
+// Очищает users (сессии удаляются каскадно) и текущую базу Redis перед каждым тестом
+func NewPostgresFactory(pool *pgxpool.Pool, redisClient redis.UniversalClient) Factory {
+	return func(t *testing.T, cfg config.Session) Repositories {
+		ctx := context.Background()
+		if _, err := pool.Exec(ctx, `TRUNCATE users CASCADE`); err != nil {
+			t.Fatalf("failed to truncate users: %v", err)
+		}
+		if err := redisClient.FlushDB(ctx).Err(); err != nil {
+			t.Fatalf("failed to flush redis: %v", err)
+		}
+		sessions, err := repos.NewSessionRepository(pool, redisClient, cfg)
+		if err != nil {
+			t.Fatalf("failed to create session repository: %v", err)
+		}
+		return Repositories{
+			Users:    repos.NewUserRepository(pool, redisClient),
+			Sessions: sessions,
+		}
+	}
+}
+
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newPhone(t *testing.T) string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1e10))
+	if err != nil {
+		t.Fatalf("failed to generate phone: %v", err)
+	}
+	return fmt.Sprintf("+7%010d", n.Int64())
+}
+
+func createUser(t *testing.T, users domain.UserRepository, role string) string {
+	t.Helper()
+	id, err := users.Create(context.Background(), domain.CreateUserDto{
+		Name:  "Test User",
+		Phone: newPhone(t),
+		Role:  &role,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return id
+}
+
+func createSession(t *testing.T, sessions domain.SessionRepository, userId, role string, tier domain.SessionTier) domain.SessionTokens {
+	t.Helper()
+	tokens, err := sessions.Create(context.Background(), domain.CreateSessionDto{
+		UserId:      userId,
+		SessionRole: role,
+		Tier:        tier,
+	})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return tokens
+}
@@ -0,0 +1,367 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/google/uuid"
+)
+
+func RunSessions(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleManager)
+		tokens := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		if tokens.SessionToken == "" || tokens.RefreshToken == "" || tokens.SessionToken == tokens.SessionId {
+			t.Fatalf("Create returned incomplete tokens: %+v", tokens)
+		}
+
+		// Роль в информации о сессии — роль сессии, а не пользователя
+		want := domain.SessionInfo{SessionId: tokens.SessionId, UserId: id, UserName: "Test User", UserRole: domain.UserRoleUser}
+		if tokens.SessionInfo != want {
+			t.Errorf("Create = %+v, want %+v", tokens.SessionInfo, want)
+		}
+		info, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken})
+		if err != nil {
+			t.Fatalf("FindSessionInfo: %v", err)
+		}
+		if info != want {
+			t.Errorf("FindSessionInfo = %+v, want %+v", info, want)
+		}
+
+		// Идентификатор сессии не является токеном
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionId})
+		expectError(t, "FindSessionInfo by session id", err, domain.ErrValidationError)
+	})
+
+	t.Run("CreateErrors", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+
+		_, err := r.Sessions.Create(ctx, domain.CreateSessionDto{UserId: uuid.NewString(), SessionRole: domain.UserRoleUser, Tier: domain.SessionTierNormal})
+		expectError(t, "Create for missing user", err, repos.ErrUserNotFound)
+		_, err = r.Sessions.Create(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleAdmin, Tier: domain.SessionTierNormal})
+		expectError(t, "Create above user role", err, repos.ErrRoleMistmatch)
+		_, err = r.Sessions.CreateWithLimit(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleManager, Tier: domain.SessionTierNormal})
+		expectError(t, "CreateWithLimit above user role", err, repos.ErrRoleMistmatch)
+		_, err = r.Sessions.Create(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleUser, Tier: "forever"})
+		expectError(t, "Create with bad tier", err, domain.ErrValidationError)
+		_, err = r.Sessions.Create(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleUser, Tier: domain.SessionTierNormal, IpAddress: "localhost"})
+		expectError(t, "Create with bad ip address", err, domain.ErrValidationError)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		badId := "not-a-uuid"
+
+		_, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: "short"})
+		expectError(t, "FindSessionInfo", err, domain.ErrValidationError)
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: "short"})
+		expectError(t, "Refresh", err, domain.ErrValidationError)
+		_, err = r.Sessions.GetUserSessionCount(ctx, domain.FindSessionWithRoleDto{Id: badId, SessionRole: domain.UserRoleUser})
+		expectError(t, "GetUserSessionCount", err, domain.ErrValidationError)
+		err = r.Sessions.DeleteOldestUserSession(ctx, domain.FindSessionWithRoleDto{Id: uuid.NewString(), SessionRole: "superuser"})
+		expectError(t, "DeleteOldestUserSession", err, domain.ErrValidationError)
+		err = r.Sessions.Delete(ctx, domain.FindSessionDto{Id: badId})
+		expectError(t, "Delete", err, domain.ErrValidationError)
+		_, err = r.Sessions.ListUserSessions(ctx, domain.FindUserDto{Id: badId})
+		expectError(t, "ListUserSessions", err, domain.ErrValidationError)
+		err = r.Sessions.RevokeUserSession(ctx, domain.FindUserSessionDto{UserId: uuid.NewString(), Id: badId})
+		expectError(t, "RevokeUserSession", err, domain.ErrValidationError)
+		err = r.Sessions.RevokeAllUserSessions(ctx, domain.FindUserDto{Id: badId})
+		expectError(t, "RevokeAllUserSessions", err, domain.ErrValidationError)
+		err = r.Sessions.RevokeOtherUserSessions(ctx, domain.FindUserSessionDto{UserId: badId, Id: uuid.NewString()})
+		expectError(t, "RevokeOtherUserSessions", err, domain.ErrValidationError)
+	})
+
+	t.Run("CountPerRole", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleAdmin)
+		createSession(t, r.Sessions, id, domain.UserRoleAdmin, domain.SessionTierNormal)
+		createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+
+		expectCount(t, r, id, domain.UserRoleAdmin, 1)
+		expectCount(t, r, id, domain.UserRoleUser, 2)
+		expectCount(t, r, id, domain.UserRoleManager, 0)
+		expectCount(t, r, uuid.NewString(), domain.UserRoleUser, 0)
+	})
+
+	t.Run("CreateWithLimitEvictsOldest", func(t *testing.T) {
+		cfg := sessionConfig()
+		r := newRepos(t, cfg)
+		id := createUser(t, r.Users, domain.UserRoleManager)
+
+		// Порядок вытеснения задает время создания, а не окно обновления:
+		// длинная сессия, созданная первой, вытесняется раньше короткой
+		first := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierLong)
+		tick(r)
+		second := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+		tick(r)
+		other := createSession(t, r.Sessions, id, domain.UserRoleManager, domain.SessionTierNormal)
+		tick(r)
+
+		created, err := r.Sessions.CreateWithLimit(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleUser, Tier: domain.SessionTierNormal})
+		if err != nil {
+			t.Fatalf("CreateWithLimit: %v", err)
+		}
+		expectCount(t, r, id, domain.UserRoleUser, cfg.SESSION_MAX_USER_SESSIONS)
+		expectSessionIds(t, r, id, created.SessionId, other.SessionId, second.SessionId)
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: first.SessionToken})
+		expectError(t, "FindSessionInfo for evicted session", err, repos.ErrSessionNotFound)
+
+		// Ограничение действует в пределах роли сессии
+		expectCount(t, r, id, domain.UserRoleManager, 1)
+	})
+
+	t.Run("CreateWithLimitIgnoresExpired", func(t *testing.T) {
+		cfg := sessionConfig()
+		r := newRepos(t, cfg)
+		if r.Advance == nil {
+			t.Skip("repositories use the real clock")
+		}
+		id := createUser(t, r.Users, domain.UserRoleUser)
+
+		// Сессия с закрытым окном обновления не занимает место под лимит,
+		// как и в GetUserSessionCount
+		createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+		r.Advance(cfg.SESSION_REFRESH_EXP_SHORT + time.Second)
+		live := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		tick(r)
+
+		created, err := r.Sessions.CreateWithLimit(ctx, domain.CreateSessionDto{UserId: id, SessionRole: domain.UserRoleUser, Tier: domain.SessionTierNormal})
+		if err != nil {
+			t.Fatalf("CreateWithLimit: %v", err)
+		}
+		expectCount(t, r, id, domain.UserRoleUser, cfg.SESSION_MAX_USER_SESSIONS)
+		for _, tokens := range []domain.SessionTokens{live, created} {
+			if _, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken}); err != nil {
+				t.Errorf("FindSessionInfo for live session: %v", err)
+			}
+		}
+	})
+
+	t.Run("CreateIgnoresLimit", func(t *testing.T) {
+		cfg := sessionConfig()
+		r := newRepos(t, cfg)
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		for i := 0; i <= cfg.SESSION_MAX_USER_SESSIONS; i++ {
+			createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		}
+		expectCount(t, r, id, domain.UserRoleUser, cfg.SESSION_MAX_USER_SESSIONS+1)
+	})
+
+	t.Run("DeleteOldestUserSession", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+
+		// Без сессий удалять нечего, это не ошибка
+		if err := r.Sessions.DeleteOldestUserSession(ctx, domain.FindSessionWithRoleDto{Id: id, SessionRole: domain.UserRoleUser}); err != nil {
+			t.Fatalf("DeleteOldestUserSession without sessions: %v", err)
+		}
+
+		// Удаляется первая созданная сессия, несмотря на длинное окно обновления
+		createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierLong)
+		tick(r)
+		short := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+		if err := r.Sessions.DeleteOldestUserSession(ctx, domain.FindSessionWithRoleDto{Id: id, SessionRole: domain.UserRoleUser}); err != nil {
+			t.Fatalf("DeleteOldestUserSession: %v", err)
+		}
+		expectSessionIds(t, r, id, short.SessionId)
+	})
+
+	t.Run("ListUserSessions", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		other := createUser(t, r.Users, domain.UserRoleUser)
+
+		short := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+		long, err := r.Sessions.Create(ctx, domain.CreateSessionDto{
+			UserId:      id,
+			SessionRole: domain.UserRoleUser,
+			Tier:        domain.SessionTierLong,
+			IpAddress:   "192.0.2.1",
+			UserAgent:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+			DeviceName:  "Work laptop",
+		})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		createSession(t, r.Sessions, other, domain.UserRoleUser, domain.SessionTierNormal)
+
+		// Новые сессии идут первыми по окну обновления
+		sessions := expectSessionIds(t, r, id, long.SessionId, short.SessionId)
+		if len(sessions) != 2 {
+			return
+		}
+		session := sessions[0]
+		if session.UserId != id || session.UserName != "Test User" || session.UserRole != domain.UserRoleUser {
+			t.Errorf("session info = %+v", session.SessionInfo)
+		}
+		if session.Tier != domain.SessionTierLong || session.IpAddress != "192.0.2.1" || session.DeviceName != "Work laptop" {
+			t.Errorf("session metadata = %+v", session)
+		}
+		if session.DeviceLabel != domain.ParseUserAgent(session.UserAgent) {
+			t.Errorf("DeviceLabel = %q, want %q", session.DeviceLabel, domain.ParseUserAgent(session.UserAgent))
+		}
+		if !session.ExpiresAt.Before(session.RefreshExpiresAt) || session.CreatedAt.After(session.ExpiresAt) {
+			t.Errorf("session timestamps = %+v", session)
+		}
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		tokens := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+
+		refreshed, err := r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: tokens.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		if refreshed.SessionInfo != tokens.SessionInfo {
+			t.Errorf("Refresh = %+v, want %+v", refreshed.SessionInfo, tokens.SessionInfo)
+		}
+		if refreshed.SessionToken == tokens.SessionToken || refreshed.RefreshToken == tokens.RefreshToken {
+			t.Errorf("Refresh did not rotate tokens")
+		}
+
+		// Старый токен сессии больше не действует, новый действует
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken})
+		expectError(t, "FindSessionInfo with old token", err, repos.ErrSessionNotFound)
+		if _, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: refreshed.SessionToken}); err != nil {
+			t.Fatalf("FindSessionInfo with new token: %v", err)
+		}
+
+		// Повторное предъявление старого refresh-токена отзывает все семейство
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: tokens.RefreshToken})
+		expectError(t, "Refresh with retired token", err, repos.ErrRefreshTokenReused)
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: refreshed.SessionToken})
+		expectError(t, "FindSessionInfo after reuse", err, repos.ErrSessionNotFound)
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: refreshed.RefreshToken})
+		expectError(t, "Refresh after reuse", err, repos.ErrSessionNotFound)
+
+		// Неизвестный токен не считается повторным предъявлением
+		unknown := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		if err := r.Sessions.Delete(ctx, domain.FindSessionDto{Id: unknown.SessionId}); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: unknown.RefreshToken})
+		expectError(t, "Refresh with unknown token", err, repos.ErrSessionNotFound)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		other := createUser(t, r.Users, domain.UserRoleUser)
+		first := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+		second := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+		third := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierLong)
+		foreign := createSession(t, r.Sessions, other, domain.UserRoleUser, domain.SessionTierNormal)
+
+		// Чужую или несуществующую сессию отозвать нельзя
+		err := r.Sessions.RevokeUserSession(ctx, domain.FindUserSessionDto{UserId: id, Id: foreign.SessionId})
+		expectError(t, "RevokeUserSession for foreign session", err, repos.ErrSessionNotFound)
+		err = r.Sessions.RevokeUserSession(ctx, domain.FindUserSessionDto{UserId: id, Id: uuid.NewString()})
+		expectError(t, "RevokeUserSession for missing session", err, repos.ErrSessionNotFound)
+
+		if err := r.Sessions.RevokeUserSession(ctx, domain.FindUserSessionDto{UserId: id, Id: first.SessionId}); err != nil {
+			t.Fatalf("RevokeUserSession: %v", err)
+		}
+		expectSessionIds(t, r, id, third.SessionId, second.SessionId)
+
+		if err := r.Sessions.RevokeOtherUserSessions(ctx, domain.FindUserSessionDto{UserId: id, Id: second.SessionId}); err != nil {
+			t.Fatalf("RevokeOtherUserSessions: %v", err)
+		}
+		expectSessionIds(t, r, id, second.SessionId)
+
+		if err := r.Sessions.RevokeAllUserSessions(ctx, domain.FindUserDto{Id: id}); err != nil {
+			t.Fatalf("RevokeAllUserSessions: %v", err)
+		}
+		expectSessionIds(t, r, id)
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: second.SessionToken})
+		expectError(t, "FindSessionInfo after RevokeAllUserSessions", err, repos.ErrSessionNotFound)
+
+		// Delete идемпотентен, сессии других пользователей не затрагиваются
+		if err := r.Sessions.Delete(ctx, domain.FindSessionDto{Id: second.SessionId}); err != nil {
+			t.Errorf("Delete of revoked session: %v", err)
+		}
+		expectSessionIds(t, r, other, foreign.SessionId)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		cfg := sessionConfig()
+		r := newRepos(t, cfg)
+		if r.Advance == nil {
+			t.Skip("repositories use the real clock")
+		}
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		tokens := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierShort)
+
+		// После истечения сессии ее можно обновить, пока открыто окно обновления
+		r.Advance(cfg.SESSION_EXP_SHORT + time.Second)
+		_, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken})
+		expectError(t, "FindSessionInfo after expiry", err, repos.ErrSessionNotFound)
+		expectCount(t, r, id, domain.UserRoleUser, 1)
+		refreshed, err := r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: tokens.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh within refresh window: %v", err)
+		}
+		if _, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: refreshed.SessionToken}); err != nil {
+			t.Fatalf("FindSessionInfo after refresh: %v", err)
+		}
+
+		// После окна обновления сессия не считается и не обновляется
+		r.Advance(cfg.SESSION_REFRESH_EXP_SHORT)
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: refreshed.RefreshToken})
+		expectError(t, "Refresh after refresh window", err, repos.ErrSessionExpired)
+		expectCount(t, r, id, domain.UserRoleUser, 0)
+		expectSessionIds(t, r, id)
+	})
+}
+
+// Разводит время создания сессий: управляемые часы сами не идут
+func tick(r Repositories) {
+	if r.Advance != nil {
+		r.Advance(time.Millisecond)
+	}
+}
+
+func expectCount(t *testing.T, r Repositories, userId, role string, want int) {
+	t.Helper()
+	count, err := r.Sessions.GetUserSessionCount(context.Background(), domain.FindSessionWithRoleDto{Id: userId, SessionRole: role})
+	if err != nil {
+		t.Fatalf("GetUserSessionCount: %v", err)
+	}
+	if count != want {
+		t.Errorf("GetUserSessionCount(%s) = %d, want %d", role, count, want)
+	}
+}
+
+// Сравнивает идентификаторы сессий пользователя с учетом порядка ListUserSessions
+func expectSessionIds(t *testing.T, r Repositories, userId string, want ...string) []domain.Session {
+	t.Helper()
+	sessions, err := r.Sessions.ListUserSessions(context.Background(), domain.FindUserDto{Id: userId})
+	if err != nil {
+		t.Fatalf("ListUserSessions: %v", err)
+	}
+	got := make([]string, len(sessions))
+	for i, session := range sessions {
+		got[i] = session.SessionId
+	}
+	if len(got) != len(want) {
+		t.Errorf("ListUserSessions = %v, want %v", got, want)
+		return sessions
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ListUserSessions = %v, want %v", got, want)
+			break
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,166 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/repos"
+	"github.com/google/uuid"
+)
+
+func RunUsers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		phone := newPhone(t)
+		id, err := r.Users.Create(ctx, domain.CreateUserDto{Name: "Test User", Phone: phone})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		want := domain.User{Id: id, Name: "Test User", Phone: phone, Role: domain.UserRoleUser}
+		user, err := r.Users.Find(ctx, domain.FindUserDto{Id: id})
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if user != want {
+			t.Errorf("Find = %+v, want %+v", user, want)
+		}
+		user, err = r.Users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: phone})
+		if err != nil {
+			t.Fatalf("FindByPhone: %v", err)
+		}
+		if user != want {
+			t.Errorf("FindByPhone = %+v, want %+v", user, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		missing := domain.FindUserDto{Id: uuid.NewString()}
+		name := "Other"
+
+		_, err := r.Users.Find(ctx, missing)
+		expectError(t, "Find", err, repos.ErrUserNotFound)
+		_, err = r.Users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: newPhone(t)})
+		expectError(t, "FindByPhone", err, repos.ErrUserNotFound)
+		err = r.Users.Update(ctx, domain.UpdateUserDto{Id: missing.Id, Name: &name})
+		expectError(t, "Update", err, repos.ErrUserNotFound)
+		err = r.Users.Delete(ctx, missing)
+		expectError(t, "Delete", err, repos.ErrUserNotFound)
+	})
+
+	t.Run("UniquePhone", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		phone := newPhone(t)
+		if _, err := r.Users.Create(ctx, domain.CreateUserDto{Name: "First", Phone: phone}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		_, err := r.Users.Create(ctx, domain.CreateUserDto{Name: "Second", Phone: phone})
+		expectError(t, "Create", err, repos.ErrUniqueViolation)
+
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		err = r.Users.Update(ctx, domain.UpdateUserDto{Id: id, Phone: &phone})
+		expectError(t, "Update", err, repos.ErrUniqueViolation)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		badName, badRole := "R2-D2", "superuser"
+
+		_, err := r.Users.Create(ctx, domain.CreateUserDto{Name: badName, Phone: newPhone(t)})
+		expectError(t, "Create with bad name", err, domain.ErrValidationError)
+		_, err = r.Users.Create(ctx, domain.CreateUserDto{Name: "Test User", Phone: "89990000000"})
+		expectError(t, "Create with bad phone", err, domain.ErrValidationError)
+		_, err = r.Users.Create(ctx, domain.CreateUserDto{Name: "Test User", Phone: newPhone(t), Role: &badRole})
+		expectError(t, "Create with bad role", err, domain.ErrValidationError)
+		_, err = r.Users.Find(ctx, domain.FindUserDto{Id: "not-a-uuid"})
+		expectError(t, "Find", err, domain.ErrValidationError)
+		_, err = r.Users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: "phone"})
+		expectError(t, "FindByPhone", err, domain.ErrValidationError)
+		err = r.Users.Update(ctx, domain.UpdateUserDto{Id: uuid.NewString(), Name: &badName})
+		expectError(t, "Update", err, domain.ErrValidationError)
+		err = r.Users.Delete(ctx, domain.FindUserDto{Id: ""})
+		expectError(t, "Delete", err, domain.ErrValidationError)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		name, phone, role := "Renamed", newPhone(t), domain.UserRoleManager
+		err := r.Users.Update(ctx, domain.UpdateUserDto{Id: id, Name: &name, Phone: &phone, Role: &role})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		want := domain.User{Id: id, Name: name, Phone: phone, Role: role}
+		user, err := r.Users.Find(ctx, domain.FindUserDto{Id: id})
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if user != want {
+			t.Errorf("Find = %+v, want %+v", user, want)
+		}
+	})
+
+	t.Run("RenamePropagatesToSessions", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		tokens := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+
+		name := "Renamed"
+		if err := r.Users.Update(ctx, domain.UpdateUserDto{Id: id, Name: &name}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		info, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken})
+		if err != nil {
+			t.Fatalf("FindSessionInfo: %v", err)
+		}
+		if info.UserName != name {
+			t.Errorf("UserName = %q, want %q", info.UserName, name)
+		}
+	})
+
+	t.Run("DemotionRevokesSessions", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleAdmin)
+		admin := createSession(t, r.Sessions, id, domain.UserRoleAdmin, domain.SessionTierNormal)
+		manager := createSession(t, r.Sessions, id, domain.UserRoleManager, domain.SessionTierNormal)
+
+		role := domain.UserRoleManager
+		if err := r.Users.Update(ctx, domain.UpdateUserDto{Id: id, Role: &role}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		_, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: admin.SessionToken})
+		expectError(t, "FindSessionInfo for admin session", err, repos.ErrSessionNotFound)
+		if _, err := r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: manager.SessionToken}); err != nil {
+			t.Errorf("FindSessionInfo for manager session: %v", err)
+		}
+	})
+
+	t.Run("DeleteCascadesSessions", func(t *testing.T) {
+		r := newRepos(t, sessionConfig())
+		id := createUser(t, r.Users, domain.UserRoleUser)
+		tokens := createSession(t, r.Sessions, id, domain.UserRoleUser, domain.SessionTierNormal)
+
+		if err := r.Users.Delete(ctx, domain.FindUserDto{Id: id}); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		_, err := r.Users.Find(ctx, domain.FindUserDto{Id: id})
+		expectError(t, "Find", err, repos.ErrUserNotFound)
+		_, err = r.Sessions.FindSessionInfo(ctx, domain.FindSessionByTokenDto{Token: tokens.SessionToken})
+		expectError(t, "FindSessionInfo", err, repos.ErrSessionNotFound)
+		_, err = r.Sessions.Refresh(ctx, domain.RefreshSessionDto{RefreshToken: tokens.RefreshToken})
+		expectError(t, "Refresh", err, repos.ErrSessionNotFound)
+	})
+}
+
+func expectError(t *testing.T, op string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s: error = %v, want %v", op, err, target)
+	}
+}
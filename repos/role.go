@@ -0,0 +1,37 @@
+package repos
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RoleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRoleRepository(pool *pgxpool.Pool) domain.RoleRepository {
+	return &RoleRepository{
+		pool: pool,
+	}
+}
+
+func (r *RoleRepository) List(ctx context.Context) ([]domain.Role, error) {
+	query := `SELECT "role_name", "role_level" FROM roles ORDER BY "role_level", "role_name"`
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	roles, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Role, error) {
+		var role domain.Role
+		err := row.Scan(&role.Name, &role.Level)
+		return role, err
+	})
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	return roles, nil
+}
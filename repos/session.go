@@ -3,98 +3,356 @@ package repos
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Grubiha/auth_session/config"
 	"github.com/Grubiha/auth_session/domain"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 
 	// "github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
 type SessionRepository struct {
 	pgPool      *pgxpool.Pool
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
+	cfg         config.Session
+	activity    *sessionActivity
+
+	rehydrateGroup singleflight.Group
 }
 
-func NewSessionRepository(pgPool *pgxpool.Pool, redisClient *redis.Client) domain.SessionRepository {
-	return &SessionRepository{
+var _ domain.SessionRepository = (*SessionRepository)(nil)
+
+func NewSessionRepository(pgPool *pgxpool.Pool, redisClient redis.UniversalClient, cfg config.Session) (*SessionRepository, error) {
+	if err := validateSessionConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	r := &SessionRepository{
 		pgPool:      pgPool,
 		redisClient: redisClient,
+		cfg:         cfg,
+	}
+	if cfg.SESSION_SLIDING {
+		r.activity = newSessionActivity()
+	}
+	return r, nil
+}
+
+// Опечатка в режимах должна останавливать запуск, а не отключать обработку
+func validateSessionConfig(cfg config.Session) error {
+	switch cfg.SESSION_RECONCILE_POSTGRES_ORPHANS {
+	case ReconcilePostgresOrphansRestore, ReconcilePostgresOrphansDelete:
+	default:
+		return fmt.Errorf("%w: unknown SESSION_RECONCILE_POSTGRES_ORPHANS %q", ErrInvalidSessionConfig, cfg.SESSION_RECONCILE_POSTGRES_ORPHANS)
 	}
+	return validateCleanupConfig(cfg)
+}
+
+func (r *SessionRepository) Create(ctx context.Context, dto domain.CreateSessionDto) (domain.SessionTokens, error) {
+	return r.create(ctx, dto, 0)
 }
 
-func (r *SessionRepository) Create(ctx context.Context, dto domain.CreateSessionDto, ttl time.Duration, refreshTtl time.Duration) (string, error) {
+func (r *SessionRepository) CreateWithLimit(ctx context.Context, dto domain.CreateSessionDto) (domain.SessionTokens, error) {
+	return r.create(ctx, dto, r.cfg.SESSION_MAX_USER_SESSIONS)
+}
+
+// limit <= 0 отключает ограничение количества сессий
+func (r *SessionRepository) create(ctx context.Context, dto domain.CreateSessionDto, limit int) (domain.SessionTokens, error) {
 	if err := dto.Validate(); err != nil {
-		return "", err
+		return domain.SessionTokens{}, err
+	}
+
+	// Открываем транзакцию PostgreSQL
+	tx, err := r.pgPool.Begin(ctx)
+	if err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Сериализуем параллельные входы одного пользователя до конца транзакции
+	if limit > 0 {
+		query := `SELECT pg_advisory_xact_lock(hashtextextended('sessions:' || $1, 0))`
+		if _, err := tx.Exec(ctx, query, dto.UserId); err != nil {
+			return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+		}
 	}
 
 	// Находим пользователя
 	query := `SELECT "user_name", "user_role" FROM users WHERE "user_id" = $1`
 	var userName, userRole string
-	err := r.pgPool.QueryRow(ctx, query, dto.UserId).Scan(&userName, &userRole)
+	err = tx.QueryRow(ctx, query, dto.UserId).Scan(&userName, &userRole)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", ErrUserNotFound
+			return domain.SessionTokens{}, ErrUserNotFound
 		}
-		return "", errors.Join(ErrPostgresQueryFailed, err)
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
 	}
 
 	// Проверяем можно ли выдать запрошенную роль
-	levels := domain.UserRolesLevel
-	if levels[userRole] < levels[dto.SessionRole] {
-		return "", ErrRoleMistmatch
+	if !domain.RoleCovers(userRole, dto.SessionRole) {
+		return domain.SessionTokens{}, ErrRoleMistmatch
 	}
 
-	// Открываем транзакцию PostgreSQL
-	tx, err := r.pgPool.Begin(ctx)
+	// Вытесняем самые старые по времени создания сессии, оставляя место под новую.
+	// Сессии с истекшим окном обновления не учитываются, как в GetUserSessionCount
+	now := time.Now()
+	var evictedHashes [][]byte
+	if limit > 0 {
+		query = `DELETE FROM sessions WHERE "session_id" IN (
+			SELECT "session_id" FROM sessions
+			WHERE "user_id" = $1 AND "session_role" = $2 AND "refresh_expires_at" > $3
+			ORDER BY "created_at" DESC
+			OFFSET $4
+		) RETURNING "token_hash"`
+		rows, err := tx.Query(ctx, query, dto.UserId, dto.SessionRole, now, limit-1)
+		if err != nil {
+			return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+		}
+		evictedHashes, err = pgx.CollectRows(rows, pgx.RowTo[[]byte])
+		if err != nil {
+			return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+		}
+	}
+
+	// Выпускаем токены сессии и обновления, в базе храним только их хеши
+	sessionToken, tokenHash, err := newSecretToken()
 	if err != nil {
-		return "", errors.Join(ErrPostgresQueryFailed, err)
+		return domain.SessionTokens{}, err
+	}
+	refreshToken, refreshTokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
 	}
-	defer tx.Rollback(ctx)
 
-	// Создаем сессию в PostgreSQL
-	expiresAt := time.Now().Add(ttl)
-	refreshExpiresAt := time.Now().Add(refreshTtl)
-	query = `INSERT INTO sessions ("user_id", "session_role", "expires_at", "refresh_expires_at") VALUES ($1, $2, $3, $4) RETURNING "session_id"`
-	var newSessionId string
-	err = tx.QueryRow(ctx, query,
+	// Создаем сессию в PostgreSQL, новая сессия открывает собственное семейство токенов
+	newSessionId := uuid.NewString()
+	ttl, refreshTtl := sessionTierTtl(r.cfg, dto.Tier)
+	expiresAt := now.Add(ttl)
+	refreshExpiresAt := now.Add(refreshTtl)
+	query = `INSERT INTO sessions (
+		"session_id", "family_id", "token_hash", "user_id", "session_role", "session_tier", "expires_at", "refresh_expires_at", "refresh_token_hash",
+		"created_at", "last_seen_at", "ip_address", "user_agent", "device_label", "device_name"
+	) VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $9, NULLIF($10, '')::inet, $11, $12, $13)`
+	_, err = tx.Exec(ctx, query,
+		newSessionId,
+		tokenHash,
 		dto.UserId,
 		dto.SessionRole,
+		dto.Tier,
 		expiresAt,
 		refreshExpiresAt,
-	).Scan(&newSessionId)
+		refreshTokenHash,
+		now,
+		dto.IpAddress,
+		dto.UserAgent,
+		domain.ParseUserAgent(dto.UserAgent),
+		dto.DeviceName,
+	)
 	if err != nil {
 		var pgxErr *pgconn.PgError
 		if errors.As(err, &pgxErr) && pgxErr.Code == "23503" {
-			return "", ErrUserNotFound
+			return domain.SessionTokens{}, ErrUserNotFound
 		}
-		return "", errors.Join(ErrPostgresQueryFailed, err)
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
 	}
 
-	// Создаем информацию о сессии в Redis
-	key := "sessions:" + newSessionId
+	// Создаем информацию о сессии в Redis и удаляем вытесненные
+	key := sessionKey(tokenHash)
 	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.HSet(ctx, key, map[string]interface{}{
-			"user_id":   dto.UserId,
-			"user_name": userName,
-			"user_role": dto.SessionRole,
-		})
+		for _, evictedHash := range evictedHashes {
+			pipe.Del(ctx, sessionKey(evictedHash))
+		}
+		pipe.HSet(ctx, key, sessionInfoFields(newSessionId, dto.UserId, userName, dto.SessionRole, dto.Tier, refreshExpiresAt))
 		pipe.Expire(ctx, key, ttl)
 		return nil
 	})
 	if err != nil {
-		return "", errors.Join(ErrRedisQueryFailed, err)
+		return domain.SessionTokens{}, errors.Join(ErrRedisQueryFailed, err)
 	}
 
 	// Подтверждаем транзакцию PostgreSQL
 	if err := tx.Commit(ctx); err != nil {
-		return "", errors.Join(ErrPostgresQueryFailed, err)
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
 	}
 
-	return newSessionId, nil
+	return domain.SessionTokens{
+		SessionInfo: domain.SessionInfo{
+			SessionId: newSessionId,
+			UserId:    dto.UserId,
+			UserName:  userName,
+			UserRole:  dto.SessionRole,
+		},
+		SessionToken: sessionToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (r *SessionRepository) Refresh(ctx context.Context, dto domain.RefreshSessionDto) (domain.SessionTokens, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	// Открываем транзакцию PostgreSQL
+	tx, err := r.pgPool.Begin(ctx)
+	if err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Находим и блокируем сессию по хешу refresh-токена
+	oldRefreshTokenHash := hashToken(dto.RefreshToken)
+	query := `SELECT s."session_id", s."family_id", s."token_hash", s."generation", s."user_id", s."session_role", s."session_tier", s."refresh_expires_at", u."user_name", u."user_role"
+		FROM sessions s JOIN users u ON u."user_id" = s."user_id"
+		WHERE s."refresh_token_hash" = $1
+		FOR UPDATE OF s`
+	var sessionId, familyId, userId, sessionRole, userName, userRole string
+	var oldTokenHash []byte
+	var tier domain.SessionTier
+	var generation int
+	var refreshExpiresAt time.Time
+	err = tx.QueryRow(ctx, query, oldRefreshTokenHash).Scan(
+		&sessionId,
+		&familyId,
+		&oldTokenHash,
+		&generation,
+		&userId,
+		&sessionRole,
+		&tier,
+		&refreshExpiresAt,
+		&userName,
+		&userRole,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.SessionTokens{}, r.revokeReusedFamily(ctx, tx, oldRefreshTokenHash)
+		}
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	// Проверяем окно обновления
+	refreshExpiresAt = localTimestamp(refreshExpiresAt)
+	now := time.Now()
+	if !refreshExpiresAt.After(now) {
+		return domain.SessionTokens{}, ErrSessionExpired
+	}
+
+	// Роль пользователя могла быть понижена после входа
+	if !domain.RoleCovers(userRole, sessionRole) {
+		return domain.SessionTokens{}, ErrRoleMistmatch
+	}
+
+	// Ротируем оба токена и продлеваем сессию на срок ее уровня,
+	// но не дальше окна обновления
+	sessionToken, tokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	refreshToken, refreshTokenHash, err := newSecretToken()
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	ttl, _ := sessionTierTtl(r.cfg, tier)
+	expiresAt := now.Add(ttl)
+	if expiresAt.After(refreshExpiresAt) {
+		expiresAt = refreshExpiresAt
+	}
+	query = `UPDATE sessions SET "token_hash" = $1, "refresh_token_hash" = $2, "expires_at" = $3, "last_seen_at" = $4, "generation" = "generation" + 1 WHERE "session_id" = $5`
+	_, err = tx.Exec(ctx, query, tokenHash, refreshTokenHash, expiresAt, now, sessionId)
+	if err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	// Запоминаем старый токен, чтобы распознать его повторное предъявление
+	query = `INSERT INTO sessions_retired_refresh_tokens ("refresh_token_hash", "session_id", "family_id", "generation") VALUES ($1, $2, $3, $4)`
+	_, err = tx.Exec(ctx, query, oldRefreshTokenHash, sessionId, familyId, generation)
+	if err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	// Переносим информацию о сессии в Redis под ключ нового токена
+	key := sessionKey(tokenHash)
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(oldTokenHash))
+		pipe.HSet(ctx, key, sessionInfoFields(sessionId, userId, userName, sessionRole, tier, refreshExpiresAt))
+		pipe.ExpireAt(ctx, key, expiresAt)
+		return nil
+	})
+	if err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	// Подтверждаем транзакцию PostgreSQL
+	if err := tx.Commit(ctx); err != nil {
+		return domain.SessionTokens{}, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return domain.SessionTokens{
+		SessionInfo: domain.SessionInfo{
+			SessionId: sessionId,
+			UserId:    userId,
+			UserName:  userName,
+			UserRole:  sessionRole,
+		},
+		SessionToken: sessionToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Предъявление устаревшего поколения refresh-токена считается кражей:
+// отзываем все семейство сессий в PostgreSQL и Redis
+func (r *SessionRepository) revokeReusedFamily(ctx context.Context, tx pgx.Tx, refreshTokenHash []byte) error {
+	query := `SELECT r."family_id", r."generation", s."generation"
+		FROM sessions_retired_refresh_tokens r JOIN sessions s ON s."session_id" = r."session_id"
+		WHERE r."refresh_token_hash" = $1
+		FOR UPDATE OF s`
+	var familyId string
+	var retiredGeneration, generation int
+	err := tx.QueryRow(ctx, query, refreshTokenHash).Scan(&familyId, &retiredGeneration, &generation)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	// Токен считается устаревшим, только если сессия уже ушла на следующее поколение
+	if retiredGeneration >= generation {
+		return ErrSessionNotFound
+	}
+
+	query = `DELETE FROM sessions WHERE "family_id" = $1 RETURNING "token_hash"`
+	rows, err := tx.Query(ctx, query, familyId)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	tokenHashes, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tokenHash := range tokenHashes {
+			pipe.Del(ctx, sessionKey(tokenHash))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return ErrRefreshTokenReused
 }
 
 func (r *SessionRepository) GetUserSessionCount(ctx context.Context, dto domain.FindSessionWithRoleDto) (int, error) {
@@ -111,87 +369,277 @@ func (r *SessionRepository) GetUserSessionCount(ctx context.Context, dto domain.
 }
 
 func (r *SessionRepository) DeleteOldestUserSession(ctx context.Context, dto domain.FindSessionWithRoleDto) error {
-	query := `SELECT "session_id" FROM sessions WHERE "user_id" = $1 AND "session_role" = $2 ORDER BY "refresh_expires_at" ASC LIMIT 1`
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+	query := `SELECT "session_id" FROM sessions
+		WHERE "user_id" = $1 AND "session_role" = $2 AND "refresh_expires_at" > $3
+		ORDER BY "created_at" ASC LIMIT 1`
 	var sessionId string
-	err := r.pgPool.QueryRow(ctx, query, dto.Id, dto.SessionRole).Scan(&sessionId)
+	err := r.pgPool.QueryRow(ctx, query, dto.Id, dto.SessionRole, time.Now()).Scan(&sessionId)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil
 		}
 		return errors.Join(ErrPostgresQueryFailed, err)
 	}
-	r.Delete(ctx, domain.FindSessionDto{Id: sessionId})
-	return nil
+	return r.Delete(ctx, domain.FindSessionDto{Id: sessionId})
 }
 
 func (r *SessionRepository) Delete(ctx context.Context, dto domain.FindSessionDto) error {
 	if err := dto.Validate(); err != nil {
 		return err
 	}
-	key := "sessions:" + dto.Id
-	err := r.redisClient.Del(ctx, key).Err()
-	if err != nil {
-		return errors.Join(ErrRedisQueryFailed, err)
-	}
-	query := `DELETE FROM sessions WHERE "session_id" = $1`
-	_, err = r.pgPool.Exec(ctx, query, dto.Id)
-	if err != nil {
-		return errors.Join(ErrPostgresQueryFailed, err)
-	}
-	return nil
+	query := `DELETE FROM sessions WHERE "session_id" = $1 RETURNING "token_hash"`
+	_, err := r.revoke(ctx, query, dto.Id)
+	return err
 }
 
-func (r *SessionRepository) FindSessionInfo(ctx context.Context, dto domain.FindSessionDto) (domain.SessionInfo, error) {
+func (r *SessionRepository) FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error) {
 	if err := dto.Validate(); err != nil {
 		return domain.SessionInfo{}, err
 	}
-	key := "sessions:" + dto.Id
+	tokenHash := hashToken(dto.Token)
+	key := sessionKey(tokenHash)
 	val, err := r.redisClient.HGetAll(ctx, key).Result()
 	if err != nil {
 		return domain.SessionInfo{}, errors.Join(ErrRedisQueryFailed, err)
 	}
 	if len(val) == 0 {
-		return domain.SessionInfo{}, ErrSessionNotFound
+		// Ключ мог пропасть при сбросе Redis, восстанавливаем его из PostgreSQL
+		return r.rehydrate(ctx, tokenHash)
 	}
+
+	// В скользящем режиме каждое обращение продлевает сессию в пределах окна обновления,
+	// запись в PostgreSQL откладывается до сброса активности
+	if r.activity != nil {
+		if err := r.slide(ctx, key, val); err != nil {
+			return domain.SessionInfo{}, err
+		}
+	}
+
 	return domain.SessionInfo{
-		UserId:   val["user_id"],
-		UserName: val["user_name"],
-		UserRole: val["user_role"],
+		SessionId: val["session_id"],
+		UserId:    val["user_id"],
+		UserName:  val["user_name"],
+		UserRole:  val["user_role"],
 	}, nil
 }
 
-// func (r *SessionRepository) DeleteByUserId(ctx context.Context, dto domain.FindSessionDto) error {
-// 	if err := dto.Validate(); err != nil {
-// 		return err
-// 	}
-
-// 	query := `DELETE FROM sessions WHERE "user_id" = $1 RETURNING "session_id"`
-// 	rows, err := r.pgPool.Query(ctx, query, dto.Id)
-// 	if err != nil {
-// 		return errors.Join(ErrPostgresQueryFailed, err)
-// 	}
-// 	defer rows.Close()
-
-// 	var keys []string
-// 	for rows.Next() {
-// 		var sessionId string
-// 		if err := rows.Scan(&sessionId); err != nil {
-// 			return errors.Join(ErrPostgresQueryFailed, err)
-// 		}
-
-// 		key := "sessions:" + sessionId
-// 		keys = append(keys, key)
-// 	}
-
-// 	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-// 		for _, key := range keys {
-// 			pipe.Del(ctx, key)
-// 		}
-// 		return nil
-// 	})
-// 	if err != nil {
-// 		return errors.Join(ErrRedisQueryFailed, err)
-// 	}
-
-// 	return nil
-// }
+func (r *SessionRepository) ListUserSessions(ctx context.Context, dto domain.FindUserDto) ([]domain.Session, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT s."session_id", s."user_id", u."user_name", s."session_role", s."session_tier", s."expires_at", s."refresh_expires_at",
+			s."created_at", s."last_seen_at", COALESCE(host(s."ip_address"), ''), s."user_agent", s."device_label", s."device_name"
+		FROM sessions s JOIN users u ON u."user_id" = s."user_id"
+		WHERE s."user_id" = $1 AND s."refresh_expires_at" > $2
+		ORDER BY s."refresh_expires_at" DESC`
+	rows, err := r.pgPool.Query(ctx, query, dto.Id, time.Now())
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Session, error) {
+		var session domain.Session
+		err := row.Scan(
+			&session.SessionId,
+			&session.UserId,
+			&session.UserName,
+			&session.UserRole,
+			&session.Tier,
+			&session.ExpiresAt,
+			&session.RefreshExpiresAt,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.IpAddress,
+			&session.UserAgent,
+			&session.DeviceLabel,
+			&session.DeviceName,
+		)
+		session.ExpiresAt = localTimestamp(session.ExpiresAt)
+		session.RefreshExpiresAt = localTimestamp(session.RefreshExpiresAt)
+		session.CreatedAt = localTimestamp(session.CreatedAt)
+		session.LastSeenAt = localTimestamp(session.LastSeenAt)
+		return session, err
+	})
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return sessions, nil
+}
+
+func (r *SessionRepository) RevokeUserSession(ctx context.Context, dto domain.FindUserSessionDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+	query := `DELETE FROM sessions WHERE "user_id" = $1 AND "session_id" = $2 RETURNING "token_hash"`
+	count, err := r.revoke(ctx, query, dto.UserId, dto.Id)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllUserSessions(ctx context.Context, dto domain.FindUserDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+	query := `DELETE FROM sessions WHERE "user_id" = $1 RETURNING "token_hash"`
+	_, err := r.revoke(ctx, query, dto.Id)
+	return err
+}
+
+func (r *SessionRepository) RevokeOtherUserSessions(ctx context.Context, dto domain.FindUserSessionDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+	query := `DELETE FROM sessions WHERE "user_id" = $1 AND "session_id" <> $2 RETURNING "token_hash"`
+	_, err := r.revoke(ctx, query, dto.UserId, dto.Id)
+	return err
+}
+
+// Удаляет сессии запросом с RETURNING "token_hash" в транзакции PostgreSQL
+// и их ключи в Redis одним пайплайном
+func (r *SessionRepository) revoke(ctx context.Context, query string, args ...interface{}) (int, error) {
+	tx, err := r.pgPool.Begin(ctx)
+	if err != nil {
+		return 0, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	tokenHashes, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return 0, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	if len(tokenHashes) == 0 {
+		return 0, nil
+	}
+
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tokenHash := range tokenHashes {
+			pipe.Del(ctx, sessionKey(tokenHash))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return len(tokenHashes), nil
+}
+
+// Ограничивает восстановление, которое не отменяется вместе с запросом
+const rehydrateTimeout = 5 * time.Second
+
+// Параллельные промахи по одной сессии выполняют один запрос к PostgreSQL
+func (r *SessionRepository) rehydrate(ctx context.Context, tokenHash []byte) (domain.SessionInfo, error) {
+	key := sessionKey(tokenHash)
+	info, err, _ := r.rehydrateGroup.Do(key, func() (interface{}, error) {
+		// Результат делят все ожидающие запросы, поэтому отмена первого из них
+		// не должна прерывать восстановление для остальных
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rehydrateTimeout)
+		defer cancel()
+
+		query := `SELECT s."session_id", s."user_id", u."user_name", s."session_role", s."session_tier", s."expires_at", s."refresh_expires_at"
+			FROM sessions s JOIN users u ON u."user_id" = s."user_id"
+			WHERE s."token_hash" = $1`
+		var info domain.SessionInfo
+		var tier domain.SessionTier
+		var expiresAt, refreshExpiresAt time.Time
+		err := r.pgPool.QueryRow(ctx, query, tokenHash).Scan(
+			&info.SessionId,
+			&info.UserId,
+			&info.UserName,
+			&info.UserRole,
+			&tier,
+			&expiresAt,
+			&refreshExpiresAt,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.SessionInfo{}, ErrSessionNotFound
+			}
+			return domain.SessionInfo{}, errors.Join(ErrPostgresQueryFailed, err)
+		}
+
+		// Истекшую сессию не восстанавливаем
+		expiresAt = localTimestamp(expiresAt)
+		refreshExpiresAt = localTimestamp(refreshExpiresAt)
+		if !expiresAt.After(time.Now()) {
+			return domain.SessionInfo{}, ErrSessionNotFound
+		}
+
+		// Восстанавливаем ключ с оставшимся временем жизни
+		_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, sessionInfoFields(info.SessionId, info.UserId, info.UserName, info.UserRole, tier, refreshExpiresAt))
+			pipe.ExpireAt(ctx, key, expiresAt)
+			return nil
+		})
+		if err != nil {
+			return domain.SessionInfo{}, errors.Join(ErrRedisQueryFailed, err)
+		}
+
+		return info, nil
+	})
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+	return info.(domain.SessionInfo), nil
+}
+
+func (r *SessionRepository) slide(ctx context.Context, key string, val map[string]string) error {
+	refreshExpiresUnix, err := strconv.ParseInt(val["refresh_expires_at"], 10, 64)
+	if err != nil {
+		// Сессия создана до появления скользящего режима
+		return nil
+	}
+
+	now := time.Now()
+	ttl, _ := sessionTierTtl(r.cfg, domain.SessionTier(val["session_tier"]))
+	expiresAt := now.Add(ttl)
+	if refreshExpiresAt := time.Unix(refreshExpiresUnix, 0); expiresAt.After(refreshExpiresAt) {
+		expiresAt = refreshExpiresAt
+	}
+
+	if err := r.redisClient.ExpireAt(ctx, key, expiresAt).Err(); err != nil {
+		return errors.Join(ErrRedisQueryFailed, err)
+	}
+	r.activity.touch(val["session_id"], now, expiresAt)
+
+	return nil
+}
+
+func sessionInfoFields(sessionId, userId, userName, sessionRole string, tier domain.SessionTier, refreshExpiresAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":         sessionId,
+		"user_id":            userId,
+		"user_name":          userName,
+		"user_role":          sessionRole,
+		"session_tier":       string(tier),
+		"refresh_expires_at": refreshExpiresAt.Unix(),
+	}
+}
+
+func sessionTierTtl(cfg config.Session, tier domain.SessionTier) (time.Duration, time.Duration) {
+	switch tier {
+	case domain.SessionTierShort:
+		return cfg.SESSION_EXP_SHORT, cfg.SESSION_REFRESH_EXP_SHORT
+	case domain.SessionTierLong:
+		return cfg.SESSION_EXP_LONG, cfg.SESSION_REFRESH_EXPLONG
+	default:
+		return cfg.SESSION_EXP, cfg.SESSION_REFRESH_EXP
+	}
+}
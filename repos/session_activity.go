@@ -0,0 +1,114 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type sessionActivityEntry struct {
+	lastSeenAt time.Time
+	expiresAt  time.Time
+}
+
+// Буфер обращений к сессиям: вместо записи в PostgreSQL на каждый запрос
+// накапливаем последние значения и сбрасываем их одним UPDATE
+type sessionActivity struct {
+	mu      sync.Mutex
+	pending map[string]sessionActivityEntry
+}
+
+func newSessionActivity() *sessionActivity {
+	return &sessionActivity{
+		pending: make(map[string]sessionActivityEntry),
+	}
+}
+
+func (a *sessionActivity) touch(sessionId string, lastSeenAt, expiresAt time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[sessionId] = sessionActivityEntry{
+		lastSeenAt: lastSeenAt,
+		expiresAt:  expiresAt,
+	}
+}
+
+func (a *sessionActivity) take() map[string]sessionActivityEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pending := a.pending
+	a.pending = make(map[string]sessionActivityEntry)
+	return pending
+}
+
+// Возвращает несброшенные записи в буфер, не затирая более свежие
+func (a *sessionActivity) restore(entries map[string]sessionActivityEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for sessionId, entry := range entries {
+		if _, ok := a.pending[sessionId]; !ok {
+			a.pending[sessionId] = entry
+		}
+	}
+}
+
+func (r *SessionRepository) FlushActivity(ctx context.Context) (int, error) {
+	if r.activity == nil {
+		return 0, nil
+	}
+
+	entries := r.activity.take()
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	sessionIds := make([]string, 0, len(entries))
+	lastSeenAts := make([]time.Time, 0, len(entries))
+	expiresAts := make([]time.Time, 0, len(entries))
+	for sessionId, entry := range entries {
+		sessionIds = append(sessionIds, sessionId)
+		lastSeenAts = append(lastSeenAts, entry.lastSeenAt)
+		expiresAts = append(expiresAts, entry.expiresAt)
+	}
+
+	query := `UPDATE sessions s SET
+			"last_seen_at" = GREATEST(s."last_seen_at", v."last_seen_at"),
+			"expires_at" = GREATEST(s."expires_at", v."expires_at")
+		FROM unnest($1::uuid[], $2::timestamp[], $3::timestamp[]) AS v("session_id", "last_seen_at", "expires_at")
+		WHERE s."session_id" = v."session_id"`
+	result, err := r.pgPool.Exec(ctx, query, sessionIds, lastSeenAts, expiresAts)
+	if err != nil {
+		r.activity.restore(entries)
+		return 0, errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// Периодически сбрасывает активность до отмены ctx, затем сбрасывает остаток
+func (r *SessionRepository) RunActivityFlusher(ctx context.Context) {
+	if r.activity == nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.SESSION_ACTIVITY_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if _, err := r.FlushActivity(flushCtx); err != nil {
+				slog.Error("failed to flush session activity", "error", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if _, err := r.FlushActivity(ctx); err != nil {
+				slog.Error("failed to flush session activity", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	SessionCleanupPgCron   = "pg_cron"
+	SessionCleanupJanitor  = "janitor"
+	SessionCleanupDisabled = "disabled"
+)
+
+// Имя задания pg_cron, которое вызывает cleanup_expired_sessions из миграции 000009
+const sessionCleanupJob = "sessions_cleanup"
+
+// Проверяется при создании репозитория: с SESSION_CLEANUP_BATCH_SIZE < 1
+// очистка не продвигалась бы, а опечатка в режиме тихо отключала бы ее
+func validateCleanupConfig(cfg config.Session) error {
+	switch cfg.SESSION_CLEANUP_MODE {
+	case SessionCleanupDisabled:
+		return nil
+	case SessionCleanupPgCron:
+		if _, err := cleanupSchedule(cfg.SESSION_CLEANUP_INTERVAL); err != nil {
+			return err
+		}
+		if cfg.SESSION_CLEANUP_TIME_ZONE != "" {
+			if _, err := cleanupTimeZone(cfg.SESSION_CLEANUP_TIME_ZONE); err != nil {
+				return err
+			}
+		}
+	case SessionCleanupJanitor:
+		if cfg.SESSION_CLEANUP_INTERVAL <= 0 {
+			return fmt.Errorf("%w: SESSION_CLEANUP_INTERVAL must be positive", ErrInvalidSessionConfig)
+		}
+	default:
+		return fmt.Errorf("%w: unknown SESSION_CLEANUP_MODE %q", ErrInvalidSessionConfig, cfg.SESSION_CLEANUP_MODE)
+	}
+	if cfg.SESSION_CLEANUP_BATCH_SIZE < 1 {
+		return fmt.Errorf("%w: SESSION_CLEANUP_BATCH_SIZE must be at least 1", ErrInvalidSessionConfig)
+	}
+	return nil
+}
+
+// Переводит интервал в расписание pg_cron. Шаги в минутах и часах должны
+// делить час и сутки, иначе cron запускал бы задание неравномерно
+func cleanupSchedule(interval time.Duration) (string, error) {
+	switch {
+	case interval <= 0:
+	case interval < time.Minute && interval%time.Second == 0:
+		return fmt.Sprintf("%d seconds", interval/time.Second), nil
+	case interval < time.Hour && interval%time.Minute == 0 && time.Hour%interval == 0:
+		return fmt.Sprintf("*/%d * * * *", interval/time.Minute), nil
+	case interval < 24*time.Hour && interval%time.Hour == 0 && 24*time.Hour%interval == 0:
+		return fmt.Sprintf("0 */%d * * *", interval/time.Hour), nil
+	case interval == 24*time.Hour:
+		return "0 0 * * *", nil
+	}
+	return "", fmt.Errorf("%w: SESSION_CLEANUP_INTERVAL %s cannot be expressed as a pg_cron schedule", ErrInvalidSessionConfig, interval)
+}
+
+// Сессии хранят местное время сервиса без пояса, поэтому функция очистки
+// сравнивает их с now() в том же поясе, а не с LOCALTIMESTAMP базы
+func cleanupTimeZone(configured string) (string, error) {
+	name := configured
+	if name == "" {
+		name = processTimeZone()
+	}
+	if name == "" {
+		// Так бывает в distroless и scratch образах, где /etc/localtime
+		// обычный файл или отсутствует
+		slog.Warn("cannot detect process time zone, session cleanup assumes UTC; set SESSION_CLEANUP_TIME_ZONE if sessions are stored in another zone")
+		return "UTC", nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf("%w: invalid SESSION_CLEANUP_TIME_ZONE %q: %v", ErrInvalidSessionConfig, name, err)
+	}
+	return name, nil
+}
+
+// Имя пояса процесса или пустая строка, если его не удается определить
+func processTimeZone() string {
+	// С TZ Go называет пояс ее значением, без TZ читает /etc/localtime и
+	// называет пояс "Local"
+	if name := time.Local.String(); name != "Local" {
+		return name
+	}
+	if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if _, zone, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return zone
+		}
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// Приводит задание pg_cron в соответствие с конфигурацией: при
+// SESSION_CLEANUP_MODE=pg_cron создает или обновляет его с текущими
+// SESSION_CLEANUP_INTERVAL и SESSION_CLEANUP_BATCH_SIZE, в остальных режимах
+// снимает, чтобы очистка не выполнялась дважды
+func (r *SessionRepository) ScheduleCleanup(ctx context.Context) error {
+	switch r.cfg.SESSION_CLEANUP_MODE {
+	case SessionCleanupPgCron:
+	case SessionCleanupJanitor, SessionCleanupDisabled:
+		return r.unscheduleCleanup(ctx)
+	default:
+		return fmt.Errorf("%w: unknown SESSION_CLEANUP_MODE %q", ErrInvalidSessionConfig, r.cfg.SESSION_CLEANUP_MODE)
+	}
+
+	schedule, err := cleanupSchedule(r.cfg.SESSION_CLEANUP_INTERVAL)
+	if err != nil {
+		return err
+	}
+	timeZone, err := cleanupTimeZone(r.cfg.SESSION_CLEANUP_TIME_ZONE)
+	if err != nil {
+		return err
+	}
+	// Команда хранится в cron.job как текст, параметры подставляются заранее.
+	// Пояс уже проверен time.LoadLocation, кавычки экранируются на всякий случай
+	command := fmt.Sprintf("SELECT cleanup_expired_sessions(%d, '%s')",
+		r.cfg.SESSION_CLEANUP_BATCH_SIZE, strings.ReplaceAll(timeZone, "'", "''"))
+
+	// Задание с тем же именем cron.schedule обновляет
+	_, err = r.pgPool.Exec(ctx, `SELECT cron.schedule($1, $2, $3)`, sessionCleanupJob, schedule, command)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) unscheduleCleanup(ctx context.Context) error {
+	// Без расширения pg_cron снимать нечего
+	var installed bool
+	err := r.pgPool.QueryRow(ctx, `SELECT to_regclass('cron.job') IS NOT NULL`).Scan(&installed)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	if !installed {
+		return nil
+	}
+	_, err = r.pgPool.Exec(ctx, `SELECT cron.unschedule(jobid) FROM cron.job WHERE jobname = $1`, sessionCleanupJob)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	return nil
+}
+
+// Удаляет сессии с истекшим окном обновления пачками по SESSION_CLEANUP_BATCH_SIZE.
+// Аналог задания pg_cron из ScheduleCleanup для окружений без расширения
+func (r *SessionRepository) CleanupExpired(ctx context.Context) (int, error) {
+	query := `DELETE FROM sessions WHERE "session_id" IN (
+		SELECT "session_id" FROM sessions
+		WHERE "refresh_expires_at" < $1
+		ORDER BY "refresh_expires_at"
+		LIMIT $2
+	) RETURNING "token_hash"`
+
+	deleted := 0
+	for {
+		rows, err := r.pgPool.Query(ctx, query, time.Now(), r.cfg.SESSION_CLEANUP_BATCH_SIZE)
+		if err != nil {
+			return deleted, errors.Join(ErrPostgresQueryFailed, err)
+		}
+		tokenHashes, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+		if err != nil {
+			return deleted, errors.Join(ErrPostgresQueryFailed, err)
+		}
+		deleted += len(tokenHashes)
+
+		// Ключи обычно уже истекли, но при скользящем режиме могли задержаться
+		if len(tokenHashes) > 0 {
+			_, err = r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, tokenHash := range tokenHashes {
+					pipe.Del(ctx, sessionKey(tokenHash))
+				}
+				return nil
+			})
+			if err != nil {
+				return deleted, errors.Join(ErrRedisQueryFailed, err)
+			}
+		}
+
+		if len(tokenHashes) == 0 || len(tokenHashes) < r.cfg.SESSION_CLEANUP_BATCH_SIZE {
+			return deleted, nil
+		}
+	}
+}
+
+// Периодически удаляет истекшие сессии, если выбран режим SESSION_CLEANUP_MODE=janitor
+func (r *SessionRepository) RunJanitor(ctx context.Context) {
+	if r.cfg.SESSION_CLEANUP_MODE != SessionCleanupJanitor {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.SESSION_CLEANUP_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := r.CleanupExpired(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("failed to cleanup expired sessions", "error", err, "deleted", deleted)
+				continue
+			}
+			slog.Info("expired sessions cleaned up", "deleted", deleted)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package repos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Grubiha/auth_session/config"
+)
+
+func TestCleanupSchedule(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		want     string
+	}{
+		{30 * time.Second, "30 seconds"},
+		{15 * time.Minute, "*/15 * * * *"},
+		{6 * time.Hour, "0 */6 * * *"},
+		{24 * time.Hour, "0 0 * * *"},
+		{0, ""},
+		{90 * time.Second, ""},
+		{7 * time.Minute, ""},
+		{5 * time.Hour, ""},
+	}
+	for _, tt := range tests {
+		got, err := cleanupSchedule(tt.interval)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidSessionConfig) {
+				t.Errorf("cleanupSchedule(%s) error = %v, want %v", tt.interval, err, ErrInvalidSessionConfig)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("cleanupSchedule(%s) = %q, %v, want %q", tt.interval, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateCleanupConfig(t *testing.T) {
+	valid := config.Session{
+		SESSION_CLEANUP_MODE:       SessionCleanupJanitor,
+		SESSION_CLEANUP_INTERVAL:   15 * time.Minute,
+		SESSION_CLEANUP_BATCH_SIZE: 1000,
+		SESSION_CLEANUP_TIME_ZONE:  "Europe/Moscow",
+	}
+	if err := validateCleanupConfig(valid); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	tests := map[string]func(cfg *config.Session){
+		"unknown mode":  func(cfg *config.Session) { cfg.SESSION_CLEANUP_MODE = "cron" },
+		"zero batch":    func(cfg *config.Session) { cfg.SESSION_CLEANUP_BATCH_SIZE = 0 },
+		"zero interval": func(cfg *config.Session) { cfg.SESSION_CLEANUP_INTERVAL = 0 },
+		"bad time zone": func(cfg *config.Session) {
+			cfg.SESSION_CLEANUP_MODE, cfg.SESSION_CLEANUP_TIME_ZONE = SessionCleanupPgCron, "Mars/Olympus"
+		},
+		"odd cron steps": func(cfg *config.Session) {
+			cfg.SESSION_CLEANUP_MODE, cfg.SESSION_CLEANUP_INTERVAL = SessionCleanupPgCron, 7*time.Minute
+		},
+	}
+	for name, mutate := range tests {
+		cfg := valid
+		mutate(&cfg)
+		if err := validateCleanupConfig(cfg); !errors.Is(err, ErrInvalidSessionConfig) {
+			t.Errorf("%s: error = %v, want %v", name, err, ErrInvalidSessionConfig)
+		}
+	}
+}
+
+func TestCleanupTimeZoneFallback(t *testing.T) {
+	// Неопределимый пояс процесса заменяется UTC и не срывает запуск
+	zone, err := cleanupTimeZone("")
+	if err != nil || zone == "" {
+		t.Fatalf("cleanupTimeZone(\"\") = %q, %v", zone, err)
+	}
+}
@@ -0,0 +1,289 @@
+package repos
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ReconcilePostgresOrphansRestore = "restore"
+	ReconcilePostgresOrphansDelete  = "delete"
+)
+
+type SessionReconcileReport struct {
+	RedisKeysScanned    int
+	RedisOrphansRemoved int
+
+	PostgresRowsScanned     int
+	PostgresOrphansFound    int
+	PostgresOrphansRemoved  int
+	PostgresOrphansRestored int
+}
+
+// Сверяет ключи "sessions:*" в Redis с таблицей sessions.
+// Ключ без строки в PostgreSQL удаляется, включая ключи старого формата
+// "sessions:<session_id>". Действующая строка без ключа восстанавливается
+// в Redis или удаляется в зависимости от SESSION_RECONCILE_POSTGRES_ORPHANS.
+// Отзыв удаляет ключ из Redis до коммита в PostgreSQL, поэтому при сбое
+// коммита restore воскрешает отозванную сессию; по умолчанию строки удаляются.
+// Кандидаты перепроверяются после SESSION_RECONCILE_GRACE, чтобы не задеть
+// сессии, которые создаются или удаляются прямо сейчас
+func (r *SessionRepository) Reconcile(ctx context.Context) (SessionReconcileReport, error) {
+	var report SessionReconcileReport
+
+	redisOrphans, err := r.findRedisOrphans(ctx, &report)
+	if err != nil {
+		return report, err
+	}
+	postgresOrphans, err := r.findPostgresOrphans(ctx, &report)
+	if err != nil {
+		return report, err
+	}
+	if len(redisOrphans) == 0 && len(postgresOrphans) == 0 {
+		return report, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return report, ctx.Err()
+	case <-time.After(r.cfg.SESSION_RECONCILE_GRACE):
+	}
+
+	// Ключи без строки в PostgreSQL
+	redisOrphans, err = r.missingInPostgres(ctx, redisOrphans)
+	if err != nil {
+		return report, err
+	}
+	if len(redisOrphans) > 0 {
+		// Ключи удаляем по одному: в кластере они лежат в разных слотах
+		_, err = r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range redisOrphans {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, errors.Join(ErrRedisQueryFailed, err)
+		}
+		report.RedisOrphansRemoved = len(redisOrphans)
+	}
+
+	// Строки без ключа в Redis
+	postgresOrphans, err = r.missingInRedis(ctx, postgresOrphans)
+	if err != nil {
+		return report, err
+	}
+	report.PostgresOrphansFound = len(postgresOrphans)
+	switch r.cfg.SESSION_RECONCILE_POSTGRES_ORPHANS {
+	case ReconcilePostgresOrphansRestore:
+		for _, tokenHash := range postgresOrphans {
+			_, err := r.rehydrate(ctx, tokenHash)
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			report.PostgresOrphansRestored++
+		}
+	case ReconcilePostgresOrphansDelete:
+		if len(postgresOrphans) > 0 {
+			query := `DELETE FROM sessions WHERE "token_hash" = ANY($1::bytea[]) RETURNING "token_hash"`
+			report.PostgresOrphansRemoved, err = r.revoke(ctx, query, postgresOrphans)
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Периодически запускает сверку до отмены ctx. Сервер ее не запускает,
+// единственная точка входа — cmd/reconciler -periodic
+func (r *SessionRepository) RunReconciler(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.SESSION_RECONCILE_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := r.Reconcile(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("failed to reconcile sessions", "error", err)
+				continue
+			}
+			slog.Info("sessions reconciled", "report", report)
+		}
+	}
+}
+
+func (r *SessionRepository) findRedisOrphans(ctx context.Context, report *SessionReconcileReport) ([]string, error) {
+	// SCAN в кластере обходит только один узел, поэтому сканируем каждый мастер
+	cluster, ok := r.redisClient.(*redis.ClusterClient)
+	if !ok {
+		return r.findNodeRedisOrphans(ctx, r.redisClient, report)
+	}
+
+	var mu sync.Mutex
+	var orphans []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		var nodeReport SessionReconcileReport
+		nodeOrphans, err := r.findNodeRedisOrphans(ctx, node, &nodeReport)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.RedisKeysScanned += nodeReport.RedisKeysScanned
+		orphans = append(orphans, nodeOrphans...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+func (r *SessionRepository) findNodeRedisOrphans(ctx context.Context, client redis.Cmdable, report *SessionReconcileReport) ([]string, error) {
+	var orphans []string
+	batch := make([]string, 0, r.cfg.SESSION_RECONCILE_BATCH_SIZE)
+
+	iter := client.Scan(ctx, 0, "sessions:*", int64(r.cfg.SESSION_RECONCILE_BATCH_SIZE)).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		report.RedisKeysScanned++
+		if len(batch) < r.cfg.SESSION_RECONCILE_BATCH_SIZE {
+			continue
+		}
+		missing, err := r.missingInPostgres(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, missing...)
+		batch = batch[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	missing, err := r.missingInPostgres(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return append(orphans, missing...), nil
+}
+
+func (r *SessionRepository) findPostgresOrphans(ctx context.Context, report *SessionReconcileReport) ([][]byte, error) {
+	var orphans [][]byte
+	lastId := uuid.Nil.String()
+	now := time.Now()
+
+	// Обходим действующие сессии по ключу, а не через OFFSET
+	query := `SELECT "session_id", "token_hash" FROM sessions WHERE "expires_at" > $1 AND "session_id" > $2 ORDER BY "session_id" LIMIT $3`
+	for {
+		rows, err := r.pgPool.Query(ctx, query, now, lastId, r.cfg.SESSION_RECONCILE_BATCH_SIZE)
+		if err != nil {
+			return nil, errors.Join(ErrPostgresQueryFailed, err)
+		}
+		var tokenHashes [][]byte
+		var sessionId string
+		var tokenHash []byte
+		_, err = pgx.ForEachRow(rows, []interface{}{&sessionId, &tokenHash}, func() error {
+			lastId = sessionId
+			tokenHashes = append(tokenHashes, tokenHash)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Join(ErrPostgresQueryFailed, err)
+		}
+		if len(tokenHashes) == 0 {
+			return orphans, nil
+		}
+		report.PostgresRowsScanned += len(tokenHashes)
+
+		missing, err := r.missingInRedis(ctx, tokenHashes)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, missing...)
+	}
+}
+
+// Возвращает ключи Redis, для которых нет строки в PostgreSQL
+func (r *SessionRepository) missingInPostgres(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var missing []string
+	keysByHash := make(map[string]string, len(keys))
+	tokenHashes := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		tokenHash, err := hex.DecodeString(strings.TrimPrefix(key, "sessions:"))
+		if err != nil || len(tokenHash) != 32 {
+			missing = append(missing, key)
+			continue
+		}
+		keysByHash[string(tokenHash)] = key
+		tokenHashes = append(tokenHashes, tokenHash)
+	}
+	if len(tokenHashes) == 0 {
+		return missing, nil
+	}
+
+	query := `SELECT "token_hash" FROM sessions WHERE "token_hash" = ANY($1::bytea[])`
+	rows, err := r.pgPool.Query(ctx, query, tokenHashes)
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return nil, errors.Join(ErrPostgresQueryFailed, err)
+	}
+	for _, tokenHash := range existing {
+		delete(keysByHash, string(tokenHash))
+	}
+	for _, key := range keysByHash {
+		missing = append(missing, key)
+	}
+
+	return missing, nil
+}
+
+// Возвращает хеши токенов сессий, для которых нет ключа в Redis
+func (r *SessionRepository) missingInRedis(ctx context.Context, tokenHashes [][]byte) ([][]byte, error) {
+	if len(tokenHashes) == 0 {
+		return nil, nil
+	}
+
+	cmds, err := r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tokenHash := range tokenHashes {
+			pipe.Exists(ctx, sessionKey(tokenHash))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	var missing [][]byte
+	for i, cmd := range cmds {
+		if cmd.(*redis.IntCmd).Val() == 0 {
+			missing = append(missing, tokenHashes[i])
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,9 @@
+package repos
+
+import "time"
+
+// Колонки timestamp хранят локальное время без зоны, а pgx читает их как UTC.
+// Возвращаем прочитанному значению локальную зону, чтобы сравнивать с time.Now()
+func localTimestamp(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
@@ -0,0 +1,30 @@
+package repos
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const secretTokenBytes = 32
+
+// Выпускает случайный токен; в PostgreSQL и Redis попадает только его хеш
+func newSecretToken() (string, []byte, error) {
+	buf := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// Ключ сессии в Redis строится по хешу токена, а не по самому токену
+func sessionKey(tokenHash []byte) string {
+	return "sessions:" + hex.EncodeToString(tokenHash)
+}
@@ -10,15 +10,26 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
+// Обновляет имя в кешированной информации о сессии, не создавая ключ заново
+var renameSessionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HSET", KEYS[1], "user_name", ARGV[1])
+end
+return 0
+`)
+
 type UserRepository struct {
-	pool *pgxpool.Pool
+	pool        *pgxpool.Pool
+	redisClient redis.UniversalClient
 }
 
-func NewUserRepository(pool *pgxpool.Pool) domain.UserRepository {
+func NewUserRepository(pool *pgxpool.Pool, redisClient redis.UniversalClient) domain.UserRepository {
 	return &UserRepository{
-		pool: pool,
+		pool:        pool,
+		redisClient: redisClient,
 	}
 }
 
@@ -59,9 +70,25 @@ func (r *UserRepository) Delete(ctx context.Context, dto domain.FindUserDto) err
 		return err
 	}
 
-	query := `DELETE FROM users WHERE "user_id" = $1`
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Сессии удаляются каскадно, поэтому забираем их идентификаторы заранее
+	query := `DELETE FROM sessions WHERE "user_id" = $1 RETURNING "token_hash"`
+	rows, err := tx.Query(ctx, query, dto.Id)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	tokenHashes, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
 
-	result, err := r.pool.Exec(ctx, query, dto.Id)
+	query = `DELETE FROM users WHERE "user_id" = $1`
+	result, err := tx.Exec(ctx, query, dto.Id)
 	if err != nil {
 		return errors.Join(ErrPostgresQueryFailed, err)
 	}
@@ -70,8 +97,21 @@ func (r *UserRepository) Delete(ctx context.Context, dto domain.FindUserDto) err
 		return ErrUserNotFound
 	}
 
-	return nil
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tokenHash := range tokenHashes {
+			pipe.Del(ctx, sessionKey(tokenHash))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Join(ErrRedisQueryFailed, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
 
+	return nil
 }
 
 func (r *UserRepository) Find(ctx context.Context, dto domain.FindUserDto) (domain.User, error) {
@@ -155,7 +195,13 @@ func (r *UserRepository) Update(ctx context.Context, dto domain.UpdateUserDto) e
 		len(args),
 	)
 
-	result, err := r.pool.Exec(ctx, query, args...)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, query, args...)
 
 	if err != nil {
 		var pgxErr *pgconn.PgError
@@ -169,6 +215,59 @@ func (r *UserRepository) Update(ctx context.Context, dto domain.UpdateUserDto) e
 		return ErrUserNotFound
 	}
 
-	return nil
+	// Сессии с ролью выше новой роли пользователя отзываем
+	var revokedHashes [][]byte
+	if dto.Role != nil {
+		var exceedingRoles []string
+		for _, role := range domain.Roles() {
+			if !domain.RoleCovers(*dto.Role, role.Name) {
+				exceedingRoles = append(exceedingRoles, role.Name)
+			}
+		}
+		query = `DELETE FROM sessions WHERE "user_id" = $1 AND "session_role" = ANY($2) RETURNING "token_hash"`
+		rows, err := tx.Query(ctx, query, dto.Id, exceedingRoles)
+		if err != nil {
+			return errors.Join(ErrPostgresQueryFailed, err)
+		}
+		revokedHashes, err = pgx.CollectRows(rows, pgx.RowTo[[]byte])
+		if err != nil {
+			return errors.Join(ErrPostgresQueryFailed, err)
+		}
+	}
+
+	// Оставшимся сессиям переписываем имя в Redis. Строки блокируются, как
+	// в Refresh, чтобы ротация не заменила token_hash до переименования ключа
+	var renamedHashes [][]byte
+	if dto.Name != nil {
+		query = `SELECT "token_hash" FROM sessions WHERE "user_id" = $1 FOR UPDATE`
+		rows, err := tx.Query(ctx, query, dto.Id)
+		if err != nil {
+			return errors.Join(ErrPostgresQueryFailed, err)
+		}
+		renamedHashes, err = pgx.CollectRows(rows, pgx.RowTo[[]byte])
+		if err != nil {
+			return errors.Join(ErrPostgresQueryFailed, err)
+		}
+	}
+
+	if len(revokedHashes) > 0 || len(renamedHashes) > 0 {
+		_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, tokenHash := range revokedHashes {
+				pipe.Del(ctx, sessionKey(tokenHash))
+			}
+			for _, tokenHash := range renamedHashes {
+				renameSessionScript.Eval(ctx, pipe, []string{sessionKey(tokenHash)}, *dto.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Join(ErrRedisQueryFailed, err)
+		}
+	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Join(ErrPostgresQueryFailed, err)
+	}
+
+	return nil
 }
@@ -1 +1,10 @@
 package services
+
+import "errors"
+
+var (
+	ErrUnknownMessageProvider = errors.New("unknown message provider")
+	ErrUnknownMessageMode     = errors.New("unknown message mode")
+	ErrNoMessageProviders     = errors.New("no message providers")
+	ErrMessageSendFailed      = errors.New("message send failed")
+)
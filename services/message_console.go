@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+type ConsoleMessageService struct {
+	logger *slog.Logger
+}
+
+func NewConsoleMessageService(logger *slog.Logger) interfaces.MessageService {
+	return &ConsoleMessageService{
+		logger: logger,
+	}
+}
+
+func (s *ConsoleMessageService) SendOTP(ctx context.Context, phone string, code string) error {
+	// Сам код пишем только на уровне debug, чтобы он не попадал в обычные логи
+	s.logger.InfoContext(ctx, "otp message", "phone", phone)
+	s.logger.DebugContext(ctx, "otp message code", "phone", phone, "code", code)
+	return nil
+}
+
+func (s *ConsoleMessageService) SendNotification(ctx context.Context, phone string, text string) error {
+	s.logger.InfoContext(ctx, "notification message", "phone", phone, "text", text)
+	return nil
+}
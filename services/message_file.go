@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+type FileMessage struct {
+	Type   string    `json:"type"`
+	Phone  string    `json:"phone"`
+	Code   string    `json:"code,omitempty"`
+	Text   string    `json:"text,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+const (
+	FileMessageTypeOtp          = "otp"
+	FileMessageTypeNotification = "notification"
+)
+
+// Пишет сообщения построчно в JSONL, чтобы e2e-тесты могли прочитать код
+type FileMessageService struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileMessageService(path string) interfaces.MessageService {
+	return &FileMessageService{
+		path: path,
+	}
+}
+
+func (s *FileMessageService) SendOTP(ctx context.Context, phone string, code string) error {
+	return s.write(FileMessage{
+		Type:   FileMessageTypeOtp,
+		Phone:  phone,
+		Code:   code,
+		SentAt: time.Now(),
+	})
+}
+
+func (s *FileMessageService) SendNotification(ctx context.Context, phone string, text string) error {
+	return s.write(FileMessage{
+		Type:   FileMessageTypeNotification,
+		Phone:  phone,
+		Text:   text,
+		SentAt: time.Now(),
+	})
+}
+
+func (s *FileMessageService) write(message FileMessage) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return errors.Join(ErrMessageSendFailed, err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Join(ErrMessageSendFailed, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return errors.Join(ErrMessageSendFailed, err)
+	}
+
+	return nil
+}
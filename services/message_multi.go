@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+// Перебирает провайдеров по порядку до первой успешной отправки
+type FailoverMessageService struct {
+	providers []interfaces.MessageService
+}
+
+// Отправляет сообщение через всех провайдеров, ошибка только если не смог ни один
+type FanoutMessageService struct {
+	providers []interfaces.MessageService
+}
+
+func NewFailoverMessageService(providers ...interfaces.MessageService) interfaces.MessageService {
+	return &FailoverMessageService{
+		providers: providers,
+	}
+}
+
+func NewFanoutMessageService(providers ...interfaces.MessageService) interfaces.MessageService {
+	return &FanoutMessageService{
+		providers: providers,
+	}
+}
+
+func (s *FailoverMessageService) SendOTP(ctx context.Context, phone string, code string) error {
+	return failover(s.providers, func(provider interfaces.MessageService) error {
+		return provider.SendOTP(ctx, phone, code)
+	})
+}
+
+func (s *FailoverMessageService) SendNotification(ctx context.Context, phone string, text string) error {
+	return failover(s.providers, func(provider interfaces.MessageService) error {
+		return provider.SendNotification(ctx, phone, text)
+	})
+}
+
+func (s *FanoutMessageService) SendOTP(ctx context.Context, phone string, code string) error {
+	return fanout(s.providers, func(provider interfaces.MessageService) error {
+		return provider.SendOTP(ctx, phone, code)
+	})
+}
+
+func (s *FanoutMessageService) SendNotification(ctx context.Context, phone string, text string) error {
+	return fanout(s.providers, func(provider interfaces.MessageService) error {
+		return provider.SendNotification(ctx, phone, text)
+	})
+}
+
+func failover(providers []interfaces.MessageService, send func(interfaces.MessageService) error) error {
+	if len(providers) == 0 {
+		return ErrNoMessageProviders
+	}
+
+	var sendErrors []error
+	for _, provider := range providers {
+		err := send(provider)
+		if err == nil {
+			return nil
+		}
+		sendErrors = append(sendErrors, err)
+	}
+
+	return errors.Join(ErrMessageSendFailed, errors.Join(sendErrors...))
+}
+
+func fanout(providers []interfaces.MessageService, send func(interfaces.MessageService) error) error {
+	if len(providers) == 0 {
+		return ErrNoMessageProviders
+	}
+
+	var sendErrors []error
+	for _, provider := range providers {
+		if err := send(provider); err != nil {
+			sendErrors = append(sendErrors, err)
+		}
+	}
+
+	if len(sendErrors) == len(providers) {
+		return errors.Join(ErrMessageSendFailed, errors.Join(sendErrors...))
+	}
+
+	return nil
+}
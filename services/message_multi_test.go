@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+type fakeProvider struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (p fakeProvider) SendOTP(ctx context.Context, phone string, code string) error {
+	*p.calls = append(*p.calls, p.name)
+	return p.err
+}
+
+func (p fakeProvider) SendNotification(ctx context.Context, phone string, text string) error {
+	*p.calls = append(*p.calls, p.name)
+	return p.err
+}
+
+// Провайдеры по описанию: nil — успешная отправка, иначе ошибка
+func fakeProviders(errs []error, calls *[]string) []interfaces.MessageService {
+	names := []string{"first", "second", "third"}
+	providers := make([]interfaces.MessageService, 0, len(errs))
+	for i, err := range errs {
+		providers = append(providers, fakeProvider{name: names[i], err: err, calls: calls})
+	}
+	return providers
+}
+
+var (
+	errFirst  = errors.New("first failed")
+	errSecond = errors.New("second failed")
+	errThird  = errors.New("third failed")
+)
+
+func TestFailoverMessageService(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls []string
+		wantErrs  []error
+	}{
+		{"no providers", nil, nil, []error{ErrNoMessageProviders}},
+		{"first succeeds", []error{nil, nil}, []string{"first"}, nil},
+		{"second succeeds", []error{errFirst, nil, nil}, []string{"first", "second"}, nil},
+		{"last succeeds", []error{errFirst, errSecond, nil}, []string{"first", "second", "third"}, nil},
+		{"all fail", []error{errFirst, errSecond, errThird}, []string{"first", "second", "third"}, []error{ErrMessageSendFailed, errFirst, errSecond, errThird}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			service := NewFailoverMessageService(fakeProviders(tt.errs, &calls)...)
+
+			err := service.SendOTP(context.Background(), "+79990000000", "123456")
+			checkSendError(t, err, tt.wantErrs)
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFanoutMessageService(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		wantErrs []error
+	}{
+		{"no providers", nil, []error{ErrNoMessageProviders}},
+		{"all succeed", []error{nil, nil, nil}, nil},
+		{"partial failure", []error{errFirst, nil, errThird}, nil},
+		{"all fail", []error{errFirst, errSecond, errThird}, []error{ErrMessageSendFailed, errFirst, errSecond, errThird}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			providers := fakeProviders(tt.errs, &calls)
+			service := NewFanoutMessageService(providers...)
+
+			err := service.SendNotification(context.Background(), "+79990000000", "text")
+			checkSendError(t, err, tt.wantErrs)
+			// Рассылка идет всем провайдерам независимо от ошибок
+			if len(calls) != len(providers) {
+				t.Errorf("calls = %v, want every provider", calls)
+			}
+		})
+	}
+}
+
+func checkSendError(t *testing.T, err error, want []error) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("error = %v, want nil", err)
+		}
+		return
+	}
+	for _, target := range want {
+		if !errors.Is(err, target) {
+			t.Errorf("error = %v, want it to wrap %v", err, target)
+		}
+	}
+}
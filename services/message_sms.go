@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Grubiha/auth_session/integrations"
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+type SmsMessageService struct {
+	client *integrations.ExolveClient
+}
+
+func NewSmsMessageService(client *integrations.ExolveClient) interfaces.MessageService {
+	return &SmsMessageService{
+		client: client,
+	}
+}
+
+func (s *SmsMessageService) SendOTP(ctx context.Context, phone string, code string) error {
+	_, err := s.client.SendSMS(ctx, phone, fmt.Sprintf("Код для входа: %s", code))
+	return err
+}
+
+func (s *SmsMessageService) SendNotification(ctx context.Context, phone string, text string) error {
+	_, err := s.client.SendSMS(ctx, phone, text)
+	return err
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/integrations"
+	"github.com/Grubiha/auth_session/interfaces"
+)
+
+const (
+	MessageProviderConsole = "console"
+	MessageProviderFile    = "file"
+	MessageProviderExolve  = "exolve"
+
+	MessageModeFailover = "failover"
+	MessageModeFanout   = "fanout"
+)
+
+func NewMessageService(cfg config.Config) (interfaces.MessageService, error) {
+	var providers []interfaces.MessageService
+	for _, name := range cfg.MESSAGE_PROVIDERS {
+		switch name {
+		case MessageProviderConsole:
+			slog.Warn("console message provider does not deliver messages, use it only for development")
+			providers = append(providers, NewConsoleMessageService(slog.Default()))
+		case MessageProviderFile:
+			providers = append(providers, NewFileMessageService(cfg.MESSAGE_FILE_PATH))
+		case MessageProviderExolve:
+			client, err := integrations.NewExolveClient(cfg.Exolve)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, NewSmsMessageService(client))
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownMessageProvider, name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, ErrNoMessageProviders
+	}
+
+	switch cfg.MESSAGE_MODE {
+	case MessageModeFailover:
+		return NewFailoverMessageService(providers...), nil
+	case MessageModeFanout:
+		return NewFanoutMessageService(providers...), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMessageMode, cfg.MESSAGE_MODE)
+	}
+}
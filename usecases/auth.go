@@ -1 +1,168 @@
 package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/Grubiha/auth_session/accesstoken"
+	"github.com/Grubiha/auth_session/config"
+	"github.com/Grubiha/auth_session/domain"
+	"github.com/Grubiha/auth_session/interfaces"
+	"github.com/Grubiha/auth_session/repos"
+)
+
+type AuthUsecase struct {
+	users    domain.UserRepository
+	sessions domain.SessionRepository
+	otps     domain.OtpRepository
+	messages interfaces.MessageService
+	cfg      config.Config
+
+	// nil, если подписанные access-токены выключены
+	accessTokens *accesstoken.Issuer
+}
+
+func NewAuthUsecase(
+	users domain.UserRepository,
+	sessions domain.SessionRepository,
+	otps domain.OtpRepository,
+	messages interfaces.MessageService,
+	accessTokens *accesstoken.Issuer,
+	cfg config.Config,
+) (*AuthUsecase, error) {
+	// Иначе каждый выпущенный код не пройдет проверку при сохранении
+	if err := domain.ValidateOtpLength(cfg.OTP_LENGTH); err != nil {
+		return nil, err
+	}
+
+	return &AuthUsecase{
+		users:        users,
+		sessions:     sessions,
+		otps:         otps,
+		messages:     messages,
+		accessTokens: accessTokens,
+		cfg:          cfg,
+	}, nil
+}
+
+func (u *AuthUsecase) StartLogin(ctx context.Context, dto domain.StartLoginDto) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	// Код отправляем только существующим пользователям, но для неизвестного
+	// номера отвечаем так же, чтобы по ответу нельзя было перебирать номера.
+	// Код выпускается в обоих случаях, иначе номера выдавала бы пауза
+	// между отправками
+	_, err := u.users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: dto.Phone})
+	userFound := err == nil
+	if err != nil && !errors.Is(err, repos.ErrUserNotFound) {
+		return err
+	}
+
+	code, err := generateOtpCode(u.cfg.OTP_LENGTH)
+	if err != nil {
+		return err
+	}
+
+	err = u.otps.Create(ctx, domain.CreateOtpDto{
+		Phone: dto.Phone,
+		Code:  code,
+	})
+	if err != nil {
+		return err
+	}
+	if !userFound {
+		return nil
+	}
+
+	return u.messages.SendOTP(ctx, dto.Phone, code)
+}
+
+func (u *AuthUsecase) VerifyLogin(ctx context.Context, dto domain.VerifyLoginDto) (domain.SessionTokens, error) {
+	if err := dto.Validate(); err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	err := u.otps.Verify(ctx, domain.VerifyOtpDto{
+		Phone: dto.Phone,
+		Code:  dto.Code,
+	})
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	user, err := u.users.FindByPhone(ctx, domain.FindUserByPhoneDto{Phone: dto.Phone})
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	tokens, err := u.sessions.CreateWithLimit(ctx, domain.CreateSessionDto{
+		UserId:      user.Id,
+		SessionRole: user.Role,
+		Tier:        dto.Tier,
+		IpAddress:   dto.IpAddress,
+		UserAgent:   dto.UserAgent,
+		DeviceName:  dto.DeviceName,
+	})
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	return u.issueAccessToken(tokens)
+}
+
+// Новый access-токен выдается только вместе с обновлением серверной сессии
+func (u *AuthUsecase) Refresh(ctx context.Context, dto domain.RefreshSessionDto) (domain.SessionTokens, error) {
+	tokens, err := u.sessions.Refresh(ctx, dto)
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+
+	return u.issueAccessToken(tokens)
+}
+
+func (u *AuthUsecase) Logout(ctx context.Context, dto domain.FindSessionDto) error {
+	return u.sessions.Delete(ctx, dto)
+}
+
+func (u *AuthUsecase) FindSessionInfo(ctx context.Context, dto domain.FindSessionByTokenDto) (domain.SessionInfo, error) {
+	return u.sessions.FindSessionInfo(ctx, dto)
+}
+
+// Ключи проверки access-токенов, false если они выключены
+func (u *AuthUsecase) Jwks() (accesstoken.Jwks, bool) {
+	if u.accessTokens == nil {
+		return accesstoken.Jwks{}, false
+	}
+	return u.accessTokens.Jwks(), true
+}
+
+func (u *AuthUsecase) issueAccessToken(tokens domain.SessionTokens) (domain.SessionTokens, error) {
+	if u.accessTokens == nil {
+		return tokens, nil
+	}
+
+	accessToken, expiresAt, err := u.accessTokens.Issue(tokens.SessionInfo)
+	if err != nil {
+		return domain.SessionTokens{}, err
+	}
+	tokens.AccessToken = accessToken
+	tokens.AccessTokenExpiresAt = expiresAt
+
+	return tokens, nil
+}
+
+func generateOtpCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
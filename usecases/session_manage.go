@@ -1 +1,33 @@
 package usecases
+
+import (
+	"context"
+
+	"github.com/Grubiha/auth_session/domain"
+)
+
+type SessionManageUsecase struct {
+	sessions domain.SessionRepository
+}
+
+func NewSessionManageUsecase(sessions domain.SessionRepository) *SessionManageUsecase {
+	return &SessionManageUsecase{
+		sessions: sessions,
+	}
+}
+
+func (u *SessionManageUsecase) List(ctx context.Context, dto domain.FindUserDto) ([]domain.Session, error) {
+	return u.sessions.ListUserSessions(ctx, dto)
+}
+
+func (u *SessionManageUsecase) Revoke(ctx context.Context, dto domain.FindUserSessionDto) error {
+	return u.sessions.RevokeUserSession(ctx, dto)
+}
+
+func (u *SessionManageUsecase) RevokeAll(ctx context.Context, dto domain.FindUserDto) error {
+	return u.sessions.RevokeAllUserSessions(ctx, dto)
+}
+
+func (u *SessionManageUsecase) RevokeOthers(ctx context.Context, dto domain.FindUserSessionDto) error {
+	return u.sessions.RevokeOtherUserSessions(ctx, dto)
+}
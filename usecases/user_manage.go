@@ -1 +1,37 @@
 package usecases
+
+import (
+	"context"
+
+	"github.com/Grubiha/auth_session/domain"
+)
+
+type UserManageUsecase struct {
+	users domain.UserRepository
+}
+
+func NewUserManageUsecase(users domain.UserRepository) *UserManageUsecase {
+	return &UserManageUsecase{
+		users: users,
+	}
+}
+
+func (u *UserManageUsecase) Create(ctx context.Context, dto domain.CreateUserDto) (string, error) {
+	return u.users.Create(ctx, dto)
+}
+
+func (u *UserManageUsecase) Find(ctx context.Context, dto domain.FindUserDto) (domain.User, error) {
+	return u.users.Find(ctx, dto)
+}
+
+func (u *UserManageUsecase) FindByPhone(ctx context.Context, dto domain.FindUserByPhoneDto) (domain.User, error) {
+	return u.users.FindByPhone(ctx, dto)
+}
+
+func (u *UserManageUsecase) Update(ctx context.Context, dto domain.UpdateUserDto) error {
+	return u.users.Update(ctx, dto)
+}
+
+func (u *UserManageUsecase) Delete(ctx context.Context, dto domain.FindUserDto) error {
+	return u.users.Delete(ctx, dto)
+}